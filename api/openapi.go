@@ -0,0 +1,10 @@
+// Package api holds the OpenAPI specs generated from the protos, see
+// scripts/gen_proto.sh.
+package api
+
+import "embed"
+
+// OpenAPI has one <service>.swagger.json per proto under openapi/.
+//
+//go:embed openapi/*.json
+var OpenAPI embed.FS
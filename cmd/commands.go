@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+	"go_user_service/config"
+)
+
+// runCommand executes one of the command line subcommands instead of
+// starting the server.
+func runCommand(cfg config.Config, name string, args []string) error {
+	switch name {
+	case "export-users":
+		return exportUsersCommand(cfg, args)
+	case "migrate":
+		return migrateCommand(cfg, args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go_user_service/config"
+	"go_user_service/genproto/admin_service"
+	"go_user_service/grpc/client"
+	"go_user_service/pkg/export"
+	"io"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// exportUsersCommand calls AdminService.ExportUsers and writes the stream to a file:
+//
+//	go_user_service export-users -format parquet -search john -out users.parquet
+func exportUsersCommand(cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("export-users", flag.ContinueOnError)
+	addr := fs.String("addr", cfg.ContentServiceHost+cfg.ContentGRPCPort, "address of the user service")
+	token := fs.String("token", os.Getenv("ACCESS_TOKEN"), "admin access token")
+	format := fs.String("format", export.FormatCSV, "csv, jsonl or parquet")
+	search := fs.String("search", "", "fullname filter, same as GetList")
+	offset := fs.Int64("offset", 1, "page from 1, same as GetList")
+	limit := fs.Int64("limit", 0, "page size, 0 exports all users")
+	out := fs.String("out", "", "output file, users<ext> by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		*out = "users" + export.Extension(*format)
+	}
+
+	// same credentials as the service's own clients, so the command works
+	// when the server requires TLS or client certificates
+	opts, err := client.DialOptions(cfg)
+	if err != nil {
+		return err
+	}
+
+	conn, err := grpc.NewClient(*addr, opts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	if *token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+*token)
+	}
+
+	stream, err := admin_service.NewAdminServiceClient(conn).ExportUsers(ctx, &admin_service.ExportUsersRequest{
+		Offset: *offset,
+		Limit:  *limit,
+		Search: *search,
+		Format: *format,
+	})
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var written int64
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		n, err := file.Write(chunk.Data)
+		if err != nil {
+			return err
+		}
+		written += int64(n)
+	}
+
+	fmt.Printf("exported %d bytes to %s\n", written, *out)
+	return file.Close()
+}
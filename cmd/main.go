@@ -2,56 +2,240 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"go_user_service/config"
 	"go_user_service/grpc"
 	"go_user_service/grpc/client"
+	"go_user_service/outbox"
+	"go_user_service/pkg/events"
+	"go_user_service/pkg/logger"
+	"go_user_service/pkg/mailer"
+	"go_user_service/pkg/metrics"
+	"go_user_service/pkg/tlsutil"
+	"go_user_service/pkg/tracing"
+	"go_user_service/pkg/webhook"
+	"go_user_service/reconcile"
+	"go_user_service/storage"
+	"go_user_service/storage/cache"
+	"go_user_service/storage/lifecycle"
 	"go_user_service/storage/postgres"
 	"go_user_service/storage/redis"
 	"net"
-
-	"github.com/saidamir98/udevs_pkg/logger"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
 	cfg := config.Load()
 
-	loggerLevel := logger.LevelDebug
+	if len(os.Args) > 1 {
+		if err := runCommand(cfg, os.Args[1], os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	log := logger.New(cfg.ServiceName, cfg.LogLevel, cfg.LogFormat)
+
+	defer log.Sync()
 
-	switch cfg.Environment {
-	case config.DebugMode:
-		loggerLevel = logger.LevelDebug
-	case config.TestMode:
-		loggerLevel = logger.LevelDebug
-	default:
-		loggerLevel = logger.LevelInfo
+	if err := checkSchema(cfg); err != nil {
+		log.Panic("checkSchema", logger.Error(err))
 	}
 
-	log := logger.NewLogger(cfg.ServiceName, loggerLevel)
+	shutdownTracing, err := tracing.New(context.Background(), cfg)
+	if err != nil {
+		log.Panic("tracing.New", logger.Error(err))
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error("tracing shutdown", logger.Error(err))
+		}
+	}()
 
-	defer logger.Cleanup(log)
-	newRedis := redis.New(cfg)
+	newRedis, err := redis.New(context.Background(), cfg)
+	if err != nil {
+		log.Panic("redis.New", logger.Error(err))
+	}
+	defer newRedis.Close()
 
-	pgStore, err := postgres.NewPostgres(context.Background(), cfg, newRedis)
+	pgStore, err := postgres.NewPostgres(context.Background(), cfg, log, newRedis)
 	if err != nil {
 		log.Panic("postgres.NewPostgres", logger.Error(err))
 	}
 	defer pgStore.CloseDB()
 
+	// events always go to the redis stream behind WatchUsers, and to the
+	// broker when one is configured
+	publisher := events.NewStreamPublisher(newRedis.Events())
+	broker, err := events.New(cfg)
+	if err != nil {
+		log.Panic("events.New", logger.Error(err))
+	}
+	if broker != nil {
+		defer broker.Close()
+		publisher = events.Multi(publisher, broker)
+	}
+
+	var strg storage.StorageI = lifecycle.New(pgStore, lifecycle.Options{CascadeTasks: cfg.TaskCascade})
+	if cfg.CacheTTL > 0 {
+		strg = cache.New(strg, newRedis, cfg.CacheTTL)
+	}
+
 	svcs, err := client.NewGrpcClients(cfg)
 	if err != nil {
 		log.Panic("client.NewGrpcClients", logger.Error(err))
 	}
+	defer svcs.Close()
+
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		log.Panic("mailer.New", logger.Error(err))
+	}
+
+	dispatcher := outbox.NewDispatcher(pgStore, log, outbox.OptionsFromConfig(cfg))
+	dispatcher.Handle(outbox.KindEmail, outbox.EmailHandler(mail, cfg.MailServiceName))
+	dispatcher.Handle(outbox.KindWebhook, outbox.WebhookHandler(pgStore, webhook.NewSender(cfg.WebhookTimeout)))
+	dispatcher.Handle(outbox.KindEvent, outbox.EventHandler(publisher))
+	if cfg.TaskCascade {
+		dispatcher.Handle(outbox.KindTaskCascade, outbox.TaskCascadeHandler(pgStore, svcs.TaskService()))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// the dispatcher gets its own context so it keeps delivering while
+	// in-flight RPCs drain, and is stopped after them
+	dispatchCtx, cancelDispatch := context.WithCancel(context.Background())
+	defer cancelDispatch()
+	dispatched := make(chan struct{})
+	go func() {
+		dispatcher.Run(dispatchCtx)
+		close(dispatched)
+	}()
+
+	if cfg.TaskCascade && cfg.TaskReconcileInterval > 0 {
+		go reconcile.NewTasks(pgStore, svcs.TaskService(), log, cfg.TaskReconcileInterval).Run(ctx)
+	}
+
+	health := grpc.NewHealth(cfg, log, pgStore, newRedis, svcs)
+	go health.Run(ctx)
+
+	serverCreds, err := tlsutil.ServerCredentials(cfg)
+	if err != nil {
+		log.Panic("tlsutil.ServerCredentials", logger.Error(err))
+	}
 
-	grpcServer := grpc.SetUpServer(cfg, log, pgStore, svcs, newRedis)
+	limiter, err := grpc.NewRateLimiter(cfg, log, newRedis)
+	if err != nil {
+		log.Panic("grpc.NewRateLimiter", logger.Error(err))
+	}
+
+	grpcServer := grpc.SetUpServer(cfg, log, strg, svcs, newRedis, health, limiter, serverCreds)
+
+	if pool, ok := pgStore.(metrics.PoolStater); ok {
+		if err := metrics.RegisterPool(pool); err != nil {
+			log.Panic("metrics.RegisterPool", logger.Error(err))
+		}
+	}
+
+	var metricsServer *http.Server
+	if cfg.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		metricsServer = serveHTTP(log, "metrics", cfg.MetricsAddr, mux)
+	}
+
+	var gatewayServer *http.Server
+	if cfg.HTTPPort != "" {
+		// the gateway keeps its connection to the grpc server until it has
+		// drained, so it doesn't use the signal context
+		gatewayCtx, cancelGateway := context.WithCancel(context.Background())
+		defer cancelGateway()
+
+		gateway, err := grpc.NewGateway(gatewayCtx, cfg)
+		if err != nil {
+			log.Panic("grpc.NewGateway", logger.Error(err))
+		}
+		gatewayServer = serveHTTP(log, "gateway", cfg.HTTPPort, gateway)
+	}
 
 	lis, err := net.Listen("tcp", cfg.ContentGRPCPort)
 	if err != nil {
 		log.Panic("net.Listen", logger.Error(err))
 	}
 
-	log.Info("GRPC: Server being started...", logger.String("port", cfg.ContentGRPCPort))
+	log.Info("GRPC: Server being started...", logger.String("port", cfg.ContentGRPCPort),
+		logger.Bool("tls", serverCreds != nil), logger.Bool("mtls", serverCreds != nil && cfg.GRPCTLSClientCAFile != ""))
+
+	served := make(chan error, 1)
+	go func() {
+		served <- grpcServer.Serve(lis)
+	}()
 
-	if err := grpcServer.Serve(lis); err != nil {
+	select {
+	case err := <-served:
 		log.Panic("grpcServer.Serve", logger.Error(err))
+	case <-ctx.Done():
+	}
+
+	log.Info("GRPC: Server shutting down...", logger.String("timeout", cfg.ShutdownTimeout.String()))
+	health.Shutdown()
+
+	// the gateway is a client of the grpc server, so it drains first
+	shutdownHTTP(log, gatewayServer, cfg.ShutdownTimeout)
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(cfg.ShutdownTimeout):
+		log.Warn("GRPC: Shutdown timed out, cancelling remaining RPCs")
+		grpcServer.Stop()
+	}
+
+	cancelDispatch()
+	<-dispatched
+
+	shutdownHTTP(log, metricsServer, 5*time.Second)
+
+	// the deferred CloseDB and Close calls release the pool, the redis client
+	// and the connections to other services
+	log.Info("GRPC: Server stopped")
+}
+
+// serveHTTP starts an http server for h on addr in the background.
+func serveHTTP(log logger.LoggerI, name, addr string, h http.Handler) *http.Server {
+	srv := &http.Server{Addr: addr, Handler: h, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		log.Info("HTTP: Server being started...", logger.String("server", name), logger.String("addr", addr))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("HTTP: ListenAndServe", logger.String("server", name), logger.Error(err))
+		}
+	}()
+
+	return srv
+}
+
+// shutdownHTTP waits up to timeout for srv's requests to finish, srv may be nil.
+func shutdownHTTP(log logger.LoggerI, srv *http.Server, timeout time.Duration) {
+	if srv == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Error("HTTP: Shutdown", logger.String("addr", srv.Addr), logger.Error(err))
 	}
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go_user_service/config"
+	"go_user_service/migrations"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// migrateCommand applies the embedded migrations:
+//
+//	go_user_service migrate up [N]
+//	go_user_service migrate down N|all
+//	go_user_service migrate status
+//	go_user_service migrate force VERSION
+func migrateCommand(cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up [N] | down N|all | status | force VERSION")
+	}
+
+	m, err := migrations.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	var n int
+	switch args[0] {
+	case "up":
+		if len(args) == 1 {
+			err = m.Up()
+			break
+		}
+		if n, err = strconv.Atoi(args[1]); err == nil {
+			err = m.Steps(n)
+		}
+	case "down":
+		if len(args) < 2 {
+			return errors.New("migrate down needs the number of steps or all")
+		}
+		if args[1] == "all" {
+			err = m.Down()
+			break
+		}
+		if n, err = strconv.Atoi(args[1]); err == nil {
+			err = m.Steps(-n)
+		}
+	case "force":
+		if len(args) < 2 {
+			return errors.New("migrate force needs a version")
+		}
+		if n, err = strconv.Atoi(args[1]); err == nil {
+			err = m.Force(n)
+		}
+	case "status":
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	latest, err := migrations.Latest()
+	if err != nil {
+		return err
+	}
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Printf("version: none, latest: %d\n", latest)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("version: %d, dirty: %v, latest: %d\n", version, dirty, latest)
+
+	return nil
+}
+
+// checkSchema applies pending migrations when auto-migrate is enabled and
+// fails if the database schema is still behind the binary.
+func checkSchema(cfg config.Config) error {
+	m, err := migrations.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if cfg.PostgresAutoMigrate {
+		if err = m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return err
+		}
+	}
+
+	return migrations.Check(m)
+}
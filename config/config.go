@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cast"
@@ -22,6 +24,11 @@ type Config struct {
 	Environment string // debug, test, release
 	Version     string
 
+	// LogLevel is debug, info, warn or error
+	LogLevel string
+	// LogFormat is json or console
+	LogFormat string
+
 	PostgresHost     string
 	PostgresPort     int
 	PostgresUser     string
@@ -31,10 +38,149 @@ type Config struct {
 	RedisPort        string
 	RedisPassword    string
 
+	// RedisMode is single, sentinel or cluster
+	RedisMode             string
+	RedisAddrs            []string
+	RedisUsername         string
+	RedisDB               int
+	RedisMasterName       string
+	RedisSentinelPassword string
+	RedisPoolSize         int
+	// RedisStreamPoolSize bounds the separate pool used for blocking stream
+	// reads, one connection per WatchUsers call
+	RedisStreamPoolSize int
+	RedisDialTimeout    time.Duration
+	RedisReadTimeout    time.Duration
+	RedisWriteTimeout   time.Duration
+	RedisTLS            bool
+	RedisTLSSkipVerify  bool
+
+	// OTPMaxAttempts is how many wrong codes are accepted before the code is dropped
+	OTPMaxAttempts int
+	// OTPLength is the number of digits in register and login codes
+	OTPLength int
+	// OTPSecret keys the HMAC of stored OTP codes and their redis keys.
+	// Changing it invalidates the codes that are still pending.
+	OTPSecret string
+
+	// MailDriver is smtp, file or memory. It defaults to smtp when SMTP_FROM
+	// or SMTP_USERNAME is set and to file otherwise
+	MailDriver      string
+	MailDropDir     string
+	MailServiceName string
+	SMTPHost        string
+	SMTPPort        int
+	SMTPUsername    string
+	SMTPPassword    string
+	SMTPFrom        string
+	// SMTPTLS is starttls, tls or none
+	SMTPTLS     string
+	SMTPTimeout time.Duration
+
+	// EventsDriver is nats, inprocess or none
+	EventsDriver        string
+	EventsSubjectPrefix string
+	NatsURL             string
+	NatsTimeout         time.Duration
+
+	// EventStreamMaxLen caps the redis stream WatchUsers reads from, which
+	// bounds how far back a resume token can go
+	EventStreamMaxLen int64
+
+	// WebhookTimeout bounds a single webhook request
+	WebhookTimeout time.Duration
+
+	// Outbox* tune the background delivery of emails and events
+	OutboxPollInterval time.Duration
+	OutboxBatchSize    int
+	OutboxMaxAttempts  int
+	OutboxBackoffBase  time.Duration
+	OutboxBackoffMax   time.Duration
+	OutboxLease        time.Duration
+	OutboxRetention    time.Duration
+
+	// CacheTTL is how long GetByID/Check results stay in redis, 0 disables the cache
+	CacheTTL time.Duration
+
 	ContentServiceHost string
 	ContentGRPCPort    string
 
+	// TaskService* point at the task service. TaskServiceTimeout bounds a
+	// call including retries, which are only made while the service is
+	// UNAVAILABLE. With TaskServiceRequired readiness also depends on it,
+	// unless TaskServiceEnabled hosts it in this process.
+	TaskServiceHost        string
+	TaskGRPCPort           string
+	TaskServiceTimeout     time.Duration
+	TaskServiceMaxAttempts int
+	TaskServiceRequired    bool
+	// TaskServiceEnabled serves the task service from this binary. The task
+	// client then defaults to this server's port.
+	TaskServiceEnabled bool
+	// TaskCascade carries user deletes, restores and purges over to their
+	// tasks through the outbox. It defaults to TaskServiceEnabled, since an
+	// external task service needs the TaskOwner RPCs for it.
+	// TaskReconcileInterval is how often tasks of deleted users are looked
+	// for, 0 disables it.
+	TaskCascade           bool
+	TaskReconcileInterval time.Duration
+
+	// GRPCKeepalive* ping idle connections to other services so dead
+	// peers are noticed before the next call
+	GRPCKeepaliveTime    time.Duration
+	GRPCKeepaliveTimeout time.Duration
+
+	// HealthCheck* control how often postgres and redis are pinged for the
+	// grpc health service
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+	// TracingExporter is otlp, stdout or none
+	TracingExporter    string
+	TracingSampleRatio float64
+	// TracingDBStatement adds the full SQL text to postgres spans. Off by
+	// default: some statements contain user input such as search text
+	TracingDBStatement bool
+	OTLPEndpoint       string
+	OTLPInsecure       bool
+
+	// GRPCTLS* turn on TLS for the gRPC server, and mutual TLS when a
+	// client CA is set
+	GRPCTLSCertFile     string
+	GRPCTLSKeyFile      string
+	GRPCTLSClientCAFile string
+	// GRPCClient* are used when calling other services and by the gateway,
+	// which calls this server. GRPCClientServerName overrides the name the
+	// server certificate is checked against, e.g. when dialling localhost.
+	GRPCClientTLS        bool
+	GRPCClientCAFile     string
+	GRPCClientCertFile   string
+	GRPCClientKeyFile    string
+	GRPCClientServerName string
+	// TLSReloadInterval is how often certificate files are checked for changes
+	TLSReloadInterval time.Duration
+
+	// RateLimits are token buckets per RPC and caller, as comma separated
+	// "<method>=<limit>/<period>" items. The method is a full method name,
+	// "Service/Method", "Service/*" or "*". Empty disables rate limiting.
+	RateLimits []string
+	// APIKeys are the keys of trusted callers. Requests that send one in
+	// x-api-key are limited per key rather than per IP, unknown keys are
+	// ignored so they can't be rotated to get around the limits.
+	APIKeys []string
+
+	// HTTPPort serves the REST/JSON gateway, empty disables it
+	HTTPPort string
+
+	// MetricsAddr is where /metrics is served over http, empty disables it
+	MetricsAddr string
+	// ShutdownTimeout is how long in-flight RPCs get to finish on SIGTERM
+	// before they are cancelled
+	ShutdownTimeout time.Duration
+
 	PostgresMaxConnections int32
+	PostgresTxIsolation    string
+	PostgresTxMaxRetries   int
+	PostgresAutoMigrate    bool
 }
 
 // Load ...
@@ -49,22 +195,141 @@ func Load() Config {
 	config.Environment = cast.ToString(getOrReturnDefaultValue("ENVIRONMENT", DebugMode))
 	config.Version = cast.ToString(getOrReturnDefaultValue("VERSION", "1.0"))
 
+	logLevel, logFormat := "info", "json"
+	if config.Environment == DebugMode || config.Environment == TestMode {
+		logLevel, logFormat = "debug", "console"
+	}
+	config.LogLevel = cast.ToString(getOrReturnDefaultValue("LOG_LEVEL", logLevel))
+	config.LogFormat = cast.ToString(getOrReturnDefaultValue("LOG_FORMAT", logFormat))
+
 	config.PostgresHost = cast.ToString(getOrReturnDefaultValue("POSTGRES_HOST", "localhost"))
 	config.PostgresPort = cast.ToInt(getOrReturnDefaultValue("POSTGRES_PORT", 5432))
 	config.PostgresUser = cast.ToString(getOrReturnDefaultValue("POSTGRES_USER", "mirodil"))
 	config.PostgresPassword = cast.ToString(getOrReturnDefaultValue("POSTGRES_PASSWORD", "1212"))
 	config.PostgresDatabase = cast.ToString(getOrReturnDefaultValue("POSTGRES_DATABASE", "to_do_list_user"))
 	config.PostgresMaxConnections = cast.ToInt32(getOrReturnDefaultValue("POSTGRES_MAX_CONNECTIONS", 30))
-	config.RedisHost = "localhost" //cast.ToString(getOrReturnDefault("REDIS_HOST", "localhost"))
-	config.RedisPort = "6379"      //cast.ToString(getOrReturnDefault("REDIS_PORT", "6379"))
-	config.RedisPassword = cast.ToString(getOrReturnDefaultValue("REDIS_PASSWORD", "password"))
+	config.PostgresTxIsolation = cast.ToString(getOrReturnDefaultValue("POSTGRES_TX_ISOLATION", "read committed"))
+	config.PostgresTxMaxRetries = cast.ToInt(getOrReturnDefaultValue("POSTGRES_TX_MAX_RETRIES", 3))
+	config.PostgresAutoMigrate = cast.ToBool(getOrReturnDefaultValue("POSTGRES_AUTO_MIGRATE", false))
+	config.RedisHost = cast.ToString(getOrReturnDefaultValue("REDIS_HOST", "localhost"))
+	config.RedisPort = cast.ToString(getOrReturnDefaultValue("REDIS_PORT", "6379"))
+	config.RedisPassword = cast.ToString(getOrReturnDefaultValue("REDIS_PASSWORD", ""))
+
+	config.RedisMode = cast.ToString(getOrReturnDefaultValue("REDIS_MODE", "single"))
+	config.RedisAddrs = splitList(cast.ToString(getOrReturnDefaultValue("REDIS_ADDRS", config.RedisHost+":"+config.RedisPort)))
+	config.RedisUsername = cast.ToString(getOrReturnDefaultValue("REDIS_USERNAME", ""))
+	config.RedisDB = cast.ToInt(getOrReturnDefaultValue("REDIS_DB", 0))
+	config.RedisMasterName = cast.ToString(getOrReturnDefaultValue("REDIS_MASTER_NAME", ""))
+	config.RedisSentinelPassword = cast.ToString(getOrReturnDefaultValue("REDIS_SENTINEL_PASSWORD", ""))
+	config.RedisPoolSize = cast.ToInt(getOrReturnDefaultValue("REDIS_POOL_SIZE", 10))
+	config.RedisStreamPoolSize = cast.ToInt(getOrReturnDefaultValue("REDIS_STREAM_POOL_SIZE", 100))
+	config.RedisDialTimeout = cast.ToDuration(getOrReturnDefaultValue("REDIS_DIAL_TIMEOUT", "5s"))
+	config.RedisReadTimeout = cast.ToDuration(getOrReturnDefaultValue("REDIS_READ_TIMEOUT", "3s"))
+	config.RedisWriteTimeout = cast.ToDuration(getOrReturnDefaultValue("REDIS_WRITE_TIMEOUT", "3s"))
+	config.RedisTLS = cast.ToBool(getOrReturnDefaultValue("REDIS_TLS", false))
+	config.RedisTLSSkipVerify = cast.ToBool(getOrReturnDefaultValue("REDIS_TLS_SKIP_VERIFY", false))
+
+	config.OTPMaxAttempts = cast.ToInt(getOrReturnDefaultValue("OTP_MAX_ATTEMPTS", 5))
+	config.OTPLength = cast.ToInt(getOrReturnDefaultValue("OTP_LENGTH", 6))
+	config.OTPSecret = cast.ToString(getOrReturnDefaultValue("OTP_SECRET", "to_do_list_otp_secret"))
+
+	config.SMTPHost = cast.ToString(getOrReturnDefaultValue("SMTP_HOST", "smtp.gmail.com"))
+	config.SMTPPort = cast.ToInt(getOrReturnDefaultValue("SMTP_PORT", 587))
+	config.SMTPUsername = cast.ToString(getOrReturnDefaultValue("SMTP_USERNAME", ""))
+	config.SMTPPassword = cast.ToString(getOrReturnDefaultValue("SMTP_PASSWORD", ""))
+	config.SMTPFrom = cast.ToString(getOrReturnDefaultValue("SMTP_FROM", config.SMTPUsername))
+	// without a sender smtp can't send anything, mails go to MAIL_DROP_DIR
+	mailDriver := "smtp"
+	if config.SMTPFrom == "" {
+		mailDriver = "file"
+	}
+	config.MailDriver = cast.ToString(getOrReturnDefaultValue("MAIL_DRIVER", mailDriver))
+	config.MailDropDir = cast.ToString(getOrReturnDefaultValue("MAIL_DROP_DIR", "./mail"))
+	config.MailServiceName = cast.ToString(getOrReturnDefaultValue("MAIL_SERVICE_NAME", "to_do_list"))
+	config.SMTPTLS = cast.ToString(getOrReturnDefaultValue("SMTP_TLS", "starttls"))
+	config.SMTPTimeout = cast.ToDuration(getOrReturnDefaultValue("SMTP_TIMEOUT", "10s"))
+
+	config.EventsDriver = cast.ToString(getOrReturnDefaultValue("EVENTS_DRIVER", "none"))
+	config.EventsSubjectPrefix = cast.ToString(getOrReturnDefaultValue("EVENTS_SUBJECT_PREFIX", "user_service"))
+	config.NatsURL = cast.ToString(getOrReturnDefaultValue("NATS_URL", "nats://localhost:4222"))
+	config.NatsTimeout = cast.ToDuration(getOrReturnDefaultValue("NATS_TIMEOUT", "5s"))
+
+	config.EventStreamMaxLen = cast.ToInt64(getOrReturnDefaultValue("EVENT_STREAM_MAX_LEN", 100000))
+
+	config.WebhookTimeout = cast.ToDuration(getOrReturnDefaultValue("WEBHOOK_TIMEOUT", "10s"))
+
+	config.OutboxPollInterval = cast.ToDuration(getOrReturnDefaultValue("OUTBOX_POLL_INTERVAL", "1s"))
+	config.OutboxBatchSize = cast.ToInt(getOrReturnDefaultValue("OUTBOX_BATCH_SIZE", 20))
+	config.OutboxMaxAttempts = cast.ToInt(getOrReturnDefaultValue("OUTBOX_MAX_ATTEMPTS", 8))
+	config.OutboxBackoffBase = cast.ToDuration(getOrReturnDefaultValue("OUTBOX_BACKOFF_BASE", "10s"))
+	config.OutboxBackoffMax = cast.ToDuration(getOrReturnDefaultValue("OUTBOX_BACKOFF_MAX", "1h"))
+	config.OutboxLease = cast.ToDuration(getOrReturnDefaultValue("OUTBOX_LEASE", "1m"))
+	config.OutboxRetention = cast.ToDuration(getOrReturnDefaultValue("OUTBOX_RETENTION", "168h"))
+
+	config.CacheTTL = cast.ToDuration(getOrReturnDefaultValue("CACHE_TTL", "5m"))
 
 	config.ContentServiceHost = cast.ToString(getOrReturnDefaultValue("USER_SERVICE_HOST", "localhost"))
 	config.ContentGRPCPort = cast.ToString(getOrReturnDefaultValue("CONTENT_GRPC_PORT", ":8081"))
 
+	config.TaskServiceHost = cast.ToString(getOrReturnDefaultValue("TASK_SERVICE_HOST", "localhost"))
+	config.TaskServiceEnabled = cast.ToBool(getOrReturnDefaultValue("TASK_SERVICE_ENABLED", false))
+	taskGRPCPort := ":8082"
+	if config.TaskServiceEnabled {
+		taskGRPCPort = config.ContentGRPCPort
+	}
+	config.TaskGRPCPort = cast.ToString(getOrReturnDefaultValue("TASK_GRPC_PORT", taskGRPCPort))
+	config.TaskServiceTimeout = cast.ToDuration(getOrReturnDefaultValue("TASK_SERVICE_TIMEOUT", "5s"))
+	config.TaskServiceMaxAttempts = cast.ToInt(getOrReturnDefaultValue("TASK_SERVICE_MAX_ATTEMPTS", 3))
+	config.TaskServiceRequired = cast.ToBool(getOrReturnDefaultValue("TASK_SERVICE_REQUIRED", false))
+	config.TaskCascade = cast.ToBool(getOrReturnDefaultValue("TASK_CASCADE", config.TaskServiceEnabled))
+	config.TaskReconcileInterval = cast.ToDuration(getOrReturnDefaultValue("TASK_RECONCILE_INTERVAL", "1h"))
+
+	config.GRPCKeepaliveTime = cast.ToDuration(getOrReturnDefaultValue("GRPC_KEEPALIVE_TIME", "30s"))
+	config.GRPCKeepaliveTimeout = cast.ToDuration(getOrReturnDefaultValue("GRPC_KEEPALIVE_TIMEOUT", "10s"))
+
+	config.HealthCheckInterval = cast.ToDuration(getOrReturnDefaultValue("HEALTH_CHECK_INTERVAL", "5s"))
+	config.HealthCheckTimeout = cast.ToDuration(getOrReturnDefaultValue("HEALTH_CHECK_TIMEOUT", "2s"))
+	config.TracingExporter = cast.ToString(getOrReturnDefaultValue("TRACING_EXPORTER", "none"))
+	config.TracingSampleRatio = cast.ToFloat64(getOrReturnDefaultValue("TRACING_SAMPLE_RATIO", 1))
+	config.TracingDBStatement = cast.ToBool(getOrReturnDefaultValue("TRACING_DB_STATEMENT", false))
+	config.OTLPEndpoint = cast.ToString(getOrReturnDefaultValue("OTLP_ENDPOINT", "localhost:4317"))
+	config.OTLPInsecure = cast.ToBool(getOrReturnDefaultValue("OTLP_INSECURE", true))
+
+	config.GRPCTLSCertFile = cast.ToString(getOrReturnDefaultValue("GRPC_TLS_CERT_FILE", ""))
+	config.GRPCTLSKeyFile = cast.ToString(getOrReturnDefaultValue("GRPC_TLS_KEY_FILE", ""))
+	config.GRPCTLSClientCAFile = cast.ToString(getOrReturnDefaultValue("GRPC_TLS_CLIENT_CA_FILE", ""))
+	config.GRPCClientTLS = cast.ToBool(getOrReturnDefaultValue("GRPC_CLIENT_TLS", false))
+	config.GRPCClientCAFile = cast.ToString(getOrReturnDefaultValue("GRPC_CLIENT_CA_FILE", ""))
+	config.GRPCClientCertFile = cast.ToString(getOrReturnDefaultValue("GRPC_CLIENT_CERT_FILE", ""))
+	config.GRPCClientKeyFile = cast.ToString(getOrReturnDefaultValue("GRPC_CLIENT_KEY_FILE", ""))
+	config.GRPCClientServerName = cast.ToString(getOrReturnDefaultValue("GRPC_CLIENT_SERVER_NAME", ""))
+	config.TLSReloadInterval = cast.ToDuration(getOrReturnDefaultValue("TLS_RELOAD_INTERVAL", "30s"))
+
+	config.RateLimits = splitList(cast.ToString(getOrReturnDefaultValue("RATE_LIMITS",
+		"UserService/Register=5/1h,AdminService/Register=5/1h,"+
+			"UserService/RegisterConfirm=10/10m,AdminService/RegisterConfirm=10/10m,"+
+			"UserService/Login=10/1m,AdminService/Login=10/1m,"+
+			"UserService/AcceptInvite=10/10m")))
+	config.APIKeys = splitList(cast.ToString(getOrReturnDefaultValue("API_KEYS", "")))
+
+	config.HTTPPort = cast.ToString(getOrReturnDefaultValue("HTTP_PORT", ":8080"))
+	config.MetricsAddr = cast.ToString(getOrReturnDefaultValue("METRICS_ADDR", ":9090"))
+	config.ShutdownTimeout = cast.ToDuration(getOrReturnDefaultValue("SHUTDOWN_TIMEOUT", "20s"))
+
 	return config
 }
 
+// splitList splits a comma separated env value, dropping empty items.
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getOrReturnDefaultValue(key string, defaultValue interface{}) interface{} {
 	val, exists := os.LookupEnv(key)
 
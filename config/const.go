@@ -9,10 +9,6 @@ const (
 	SUPERADMIN_ROLE     = "superadmin"
 	ADMIN_ROLE          = "admin"
 	USER_ROLE           = "user"
-	SmtpServer          = "smtp.gmail.com"
-	SmtpPort            = "587"
-	SmtpUsername        = "mirsadikovmirodil52@gmail.com"
-	SmtpPassword        = "jrko xhrd tzst ukxl"
 )
 
 var SignedKey = []byte("MGJd@Ro]yKoCc)mVY1^c:upz~4rn9Pt!hYd]>c8dt#+%")
@@ -26,10 +26,20 @@ type TaskServiceClient interface {
 	Create(ctx context.Context, in *CreateTask, opts ...grpc.CallOption) (*GetTask, error)
 	GetByID(ctx context.Context, in *TaskPrimaryKey, opts ...grpc.CallOption) (*GetTask, error)
 	GetByExternalId(ctx context.Context, in *TaskPrimaryKey, opts ...grpc.CallOption) (*GetTask, error)
+	// Update replaces user_id, title and deadline. The status is changed
+	// with ChangeStatus and the description is kept as it is.
 	Update(ctx context.Context, in *UpdateTask, opts ...grpc.CallOption) (*GetTask, error)
 	ChangeStatus(ctx context.Context, in *TaskChangeStatus, opts ...grpc.CallOption) (*TaskChangeStatusResp, error)
 	Delete(ctx context.Context, in *TaskPrimaryKey, opts ...grpc.CallOption) (*empty.Empty, error)
 	GetList(ctx context.Context, in *GetListTaskRequest, opts ...grpc.CallOption) (*GetListTaskResponse, error)
+	// DeleteByOwner deletes the active tasks of a user, RestoreByOwner brings
+	// back the ones deleted this way and PurgeByOwner removes all of them for
+	// good. All three can be repeated safely. The user service only calls
+	// them with TASK_CASCADE, which is on by default only when it hosts the
+	// task service itself.
+	DeleteByOwner(ctx context.Context, in *TaskOwner, opts ...grpc.CallOption) (*TaskOwnerResp, error)
+	RestoreByOwner(ctx context.Context, in *TaskOwner, opts ...grpc.CallOption) (*TaskOwnerResp, error)
+	PurgeByOwner(ctx context.Context, in *TaskOwner, opts ...grpc.CallOption) (*TaskOwnerResp, error)
 }
 
 type taskServiceClient struct {
@@ -103,6 +113,33 @@ func (c *taskServiceClient) GetList(ctx context.Context, in *GetListTaskRequest,
 	return out, nil
 }
 
+func (c *taskServiceClient) DeleteByOwner(ctx context.Context, in *TaskOwner, opts ...grpc.CallOption) (*TaskOwnerResp, error) {
+	out := new(TaskOwnerResp)
+	err := c.cc.Invoke(ctx, "/task_service_go.TaskService/DeleteByOwner", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) RestoreByOwner(ctx context.Context, in *TaskOwner, opts ...grpc.CallOption) (*TaskOwnerResp, error) {
+	out := new(TaskOwnerResp)
+	err := c.cc.Invoke(ctx, "/task_service_go.TaskService/RestoreByOwner", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) PurgeByOwner(ctx context.Context, in *TaskOwner, opts ...grpc.CallOption) (*TaskOwnerResp, error) {
+	out := new(TaskOwnerResp)
+	err := c.cc.Invoke(ctx, "/task_service_go.TaskService/PurgeByOwner", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TaskServiceServer is the server API for TaskService service.
 // All implementations should embed UnimplementedTaskServiceServer
 // for forward compatibility
@@ -110,10 +147,20 @@ type TaskServiceServer interface {
 	Create(context.Context, *CreateTask) (*GetTask, error)
 	GetByID(context.Context, *TaskPrimaryKey) (*GetTask, error)
 	GetByExternalId(context.Context, *TaskPrimaryKey) (*GetTask, error)
+	// Update replaces user_id, title and deadline. The status is changed
+	// with ChangeStatus and the description is kept as it is.
 	Update(context.Context, *UpdateTask) (*GetTask, error)
 	ChangeStatus(context.Context, *TaskChangeStatus) (*TaskChangeStatusResp, error)
 	Delete(context.Context, *TaskPrimaryKey) (*empty.Empty, error)
 	GetList(context.Context, *GetListTaskRequest) (*GetListTaskResponse, error)
+	// DeleteByOwner deletes the active tasks of a user, RestoreByOwner brings
+	// back the ones deleted this way and PurgeByOwner removes all of them for
+	// good. All three can be repeated safely. The user service only calls
+	// them with TASK_CASCADE, which is on by default only when it hosts the
+	// task service itself.
+	DeleteByOwner(context.Context, *TaskOwner) (*TaskOwnerResp, error)
+	RestoreByOwner(context.Context, *TaskOwner) (*TaskOwnerResp, error)
+	PurgeByOwner(context.Context, *TaskOwner) (*TaskOwnerResp, error)
 }
 
 // UnimplementedTaskServiceServer should be embedded to have forward compatible implementations.
@@ -141,6 +188,15 @@ func (UnimplementedTaskServiceServer) Delete(context.Context, *TaskPrimaryKey) (
 func (UnimplementedTaskServiceServer) GetList(context.Context, *GetListTaskRequest) (*GetListTaskResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetList not implemented")
 }
+func (UnimplementedTaskServiceServer) DeleteByOwner(context.Context, *TaskOwner) (*TaskOwnerResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteByOwner not implemented")
+}
+func (UnimplementedTaskServiceServer) RestoreByOwner(context.Context, *TaskOwner) (*TaskOwnerResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreByOwner not implemented")
+}
+func (UnimplementedTaskServiceServer) PurgeByOwner(context.Context, *TaskOwner) (*TaskOwnerResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeByOwner not implemented")
+}
 
 // UnsafeTaskServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to TaskServiceServer will
@@ -279,6 +335,60 @@ func _TaskService_GetList_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TaskService_DeleteByOwner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskOwner)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).DeleteByOwner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/task_service_go.TaskService/DeleteByOwner",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).DeleteByOwner(ctx, req.(*TaskOwner))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_RestoreByOwner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskOwner)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).RestoreByOwner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/task_service_go.TaskService/RestoreByOwner",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).RestoreByOwner(ctx, req.(*TaskOwner))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_PurgeByOwner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskOwner)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).PurgeByOwner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/task_service_go.TaskService/PurgeByOwner",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).PurgeByOwner(ctx, req.(*TaskOwner))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // TaskService_ServiceDesc is the grpc.ServiceDesc for TaskService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -314,6 +424,18 @@ var TaskService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetList",
 			Handler:    _TaskService_GetList_Handler,
 		},
+		{
+			MethodName: "DeleteByOwner",
+			Handler:    _TaskService_DeleteByOwner_Handler,
+		},
+		{
+			MethodName: "RestoreByOwner",
+			Handler:    _TaskService_RestoreByOwner_Handler,
+		},
+		{
+			MethodName: "PurgeByOwner",
+			Handler:    _TaskService_PurgeByOwner_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "task.proto",
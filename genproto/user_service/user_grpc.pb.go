@@ -28,11 +28,20 @@ type UserServiceClient interface {
 	GetList(ctx context.Context, in *GetListUserRequest, opts ...grpc.CallOption) (*GetListUserResponse, error)
 	Update(ctx context.Context, in *UpdateUser, opts ...grpc.CallOption) (*GetUser, error)
 	Delete(ctx context.Context, in *UserPrimaryKey, opts ...grpc.CallOption) (*empty.Empty, error)
+	// Restore undoes Delete.
+	Restore(ctx context.Context, in *UserPrimaryKey, opts ...grpc.CallOption) (*empty.Empty, error)
+	// Purge removes a user for good, deleted or not.
+	Purge(ctx context.Context, in *UserPrimaryKey, opts ...grpc.CallOption) (*empty.Empty, error)
 	Check(ctx context.Context, in *UserPrimaryKey, opts ...grpc.CallOption) (*CheckUserResp, error)
 	Login(ctx context.Context, in *UserLoginRequest, opts ...grpc.CallOption) (*UserLoginResponse, error)
 	Register(ctx context.Context, in *UserRegisterRequest, opts ...grpc.CallOption) (*empty.Empty, error)
 	RegisterConfirm(ctx context.Context, in *UserRegisterConfRequest, opts ...grpc.CallOption) (*UserLoginResponse, error)
 	ChangePassword(ctx context.Context, in *UserChangePassword, opts ...grpc.CallOption) (*UserChangePasswordResp, error)
+	// AcceptInvite sets the first password of an imported user and uses up
+	// the invite token.
+	AcceptInvite(ctx context.Context, in *UserAcceptInviteRequest, opts ...grpc.CallOption) (*UserChangePasswordResp, error)
+	ExportMyData(ctx context.Context, in *ExportMyDataRequest, opts ...grpc.CallOption) (UserService_ExportMyDataClient, error)
+	WatchUsers(ctx context.Context, in *WatchUsersRequest, opts ...grpc.CallOption) (UserService_WatchUsersClient, error)
 }
 
 type userServiceClient struct {
@@ -88,6 +97,24 @@ func (c *userServiceClient) Delete(ctx context.Context, in *UserPrimaryKey, opts
 	return out, nil
 }
 
+func (c *userServiceClient) Restore(ctx context.Context, in *UserPrimaryKey, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/user_service_go.UserService/Restore", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) Purge(ctx context.Context, in *UserPrimaryKey, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/user_service_go.UserService/Purge", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *userServiceClient) Check(ctx context.Context, in *UserPrimaryKey, opts ...grpc.CallOption) (*CheckUserResp, error) {
 	out := new(CheckUserResp)
 	err := c.cc.Invoke(ctx, "/user_service_go.UserService/Check", in, out, opts...)
@@ -133,6 +160,79 @@ func (c *userServiceClient) ChangePassword(ctx context.Context, in *UserChangePa
 	return out, nil
 }
 
+func (c *userServiceClient) AcceptInvite(ctx context.Context, in *UserAcceptInviteRequest, opts ...grpc.CallOption) (*UserChangePasswordResp, error) {
+	out := new(UserChangePasswordResp)
+	err := c.cc.Invoke(ctx, "/user_service_go.UserService/AcceptInvite", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ExportMyData(ctx context.Context, in *ExportMyDataRequest, opts ...grpc.CallOption) (UserService_ExportMyDataClient, error) {
+	stream, err := c.cc.NewStream(ctx, &UserService_ServiceDesc.Streams[0], "/user_service_go.UserService/ExportMyData", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &userServiceExportMyDataClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type UserService_ExportMyDataClient interface {
+	Recv() (*DataExportChunk, error)
+	grpc.ClientStream
+}
+
+type userServiceExportMyDataClient struct {
+	grpc.ClientStream
+}
+
+func (x *userServiceExportMyDataClient) Recv() (*DataExportChunk, error) {
+	m := new(DataExportChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *userServiceClient) WatchUsers(ctx context.Context, in *WatchUsersRequest, opts ...grpc.CallOption) (UserService_WatchUsersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &UserService_ServiceDesc.Streams[1], "/user_service_go.UserService/WatchUsers", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &userServiceWatchUsersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type UserService_WatchUsersClient interface {
+	Recv() (*UserChange, error)
+	grpc.ClientStream
+}
+
+type userServiceWatchUsersClient struct {
+	grpc.ClientStream
+}
+
+func (x *userServiceWatchUsersClient) Recv() (*UserChange, error) {
+	m := new(UserChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // UserServiceServer is the server API for UserService service.
 // All implementations should embed UnimplementedUserServiceServer
 // for forward compatibility
@@ -142,11 +242,20 @@ type UserServiceServer interface {
 	GetList(context.Context, *GetListUserRequest) (*GetListUserResponse, error)
 	Update(context.Context, *UpdateUser) (*GetUser, error)
 	Delete(context.Context, *UserPrimaryKey) (*empty.Empty, error)
+	// Restore undoes Delete.
+	Restore(context.Context, *UserPrimaryKey) (*empty.Empty, error)
+	// Purge removes a user for good, deleted or not.
+	Purge(context.Context, *UserPrimaryKey) (*empty.Empty, error)
 	Check(context.Context, *UserPrimaryKey) (*CheckUserResp, error)
 	Login(context.Context, *UserLoginRequest) (*UserLoginResponse, error)
 	Register(context.Context, *UserRegisterRequest) (*empty.Empty, error)
 	RegisterConfirm(context.Context, *UserRegisterConfRequest) (*UserLoginResponse, error)
 	ChangePassword(context.Context, *UserChangePassword) (*UserChangePasswordResp, error)
+	// AcceptInvite sets the first password of an imported user and uses up
+	// the invite token.
+	AcceptInvite(context.Context, *UserAcceptInviteRequest) (*UserChangePasswordResp, error)
+	ExportMyData(*ExportMyDataRequest, UserService_ExportMyDataServer) error
+	WatchUsers(*WatchUsersRequest, UserService_WatchUsersServer) error
 }
 
 // UnimplementedUserServiceServer should be embedded to have forward compatible implementations.
@@ -168,6 +277,12 @@ func (UnimplementedUserServiceServer) Update(context.Context, *UpdateUser) (*Get
 func (UnimplementedUserServiceServer) Delete(context.Context, *UserPrimaryKey) (*empty.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
 }
+func (UnimplementedUserServiceServer) Restore(context.Context, *UserPrimaryKey) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Restore not implemented")
+}
+func (UnimplementedUserServiceServer) Purge(context.Context, *UserPrimaryKey) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Purge not implemented")
+}
 func (UnimplementedUserServiceServer) Check(context.Context, *UserPrimaryKey) (*CheckUserResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Check not implemented")
 }
@@ -183,6 +298,15 @@ func (UnimplementedUserServiceServer) RegisterConfirm(context.Context, *UserRegi
 func (UnimplementedUserServiceServer) ChangePassword(context.Context, *UserChangePassword) (*UserChangePasswordResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ChangePassword not implemented")
 }
+func (UnimplementedUserServiceServer) AcceptInvite(context.Context, *UserAcceptInviteRequest) (*UserChangePasswordResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcceptInvite not implemented")
+}
+func (UnimplementedUserServiceServer) ExportMyData(*ExportMyDataRequest, UserService_ExportMyDataServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExportMyData not implemented")
+}
+func (UnimplementedUserServiceServer) WatchUsers(*WatchUsersRequest, UserService_WatchUsersServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchUsers not implemented")
+}
 
 // UnsafeUserServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to UserServiceServer will
@@ -285,6 +409,42 @@ func _UserService_Delete_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserService_Restore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserPrimaryKey)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).Restore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/user_service_go.UserService/Restore",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).Restore(ctx, req.(*UserPrimaryKey))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_Purge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserPrimaryKey)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).Purge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/user_service_go.UserService/Purge",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).Purge(ctx, req.(*UserPrimaryKey))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _UserService_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UserPrimaryKey)
 	if err := dec(in); err != nil {
@@ -375,6 +535,66 @@ func _UserService_ChangePassword_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserService_AcceptInvite_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserAcceptInviteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).AcceptInvite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/user_service_go.UserService/AcceptInvite",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).AcceptInvite(ctx, req.(*UserAcceptInviteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ExportMyData_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportMyDataRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UserServiceServer).ExportMyData(m, &userServiceExportMyDataServer{stream})
+}
+
+type UserService_ExportMyDataServer interface {
+	Send(*DataExportChunk) error
+	grpc.ServerStream
+}
+
+type userServiceExportMyDataServer struct {
+	grpc.ServerStream
+}
+
+func (x *userServiceExportMyDataServer) Send(m *DataExportChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _UserService_WatchUsers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchUsersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UserServiceServer).WatchUsers(m, &userServiceWatchUsersServer{stream})
+}
+
+type UserService_WatchUsersServer interface {
+	Send(*UserChange) error
+	grpc.ServerStream
+}
+
+type userServiceWatchUsersServer struct {
+	grpc.ServerStream
+}
+
+func (x *userServiceWatchUsersServer) Send(m *UserChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // UserService_ServiceDesc is the grpc.ServiceDesc for UserService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -402,6 +622,14 @@ var UserService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Delete",
 			Handler:    _UserService_Delete_Handler,
 		},
+		{
+			MethodName: "Restore",
+			Handler:    _UserService_Restore_Handler,
+		},
+		{
+			MethodName: "Purge",
+			Handler:    _UserService_Purge_Handler,
+		},
 		{
 			MethodName: "Check",
 			Handler:    _UserService_Check_Handler,
@@ -422,7 +650,22 @@ var UserService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ChangePassword",
 			Handler:    _UserService_ChangePassword_Handler,
 		},
+		{
+			MethodName: "AcceptInvite",
+			Handler:    _UserService_AcceptInvite_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportMyData",
+			Handler:       _UserService_ExportMyData_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchUsers",
+			Handler:       _UserService_WatchUsers_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "user.proto",
 }
@@ -1,12 +1,128 @@
 package client
 
-import "go_user_service/config"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go_user_service/config"
+	"go_user_service/genproto/task_service"
+	"go_user_service/pkg/tlsutil"
 
-type ServiceManagerI interface{}
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+)
 
-type grpcClients struct{}
+type ServiceManagerI interface {
+	TaskService() task_service.TaskServiceClient
+	// Ping asks the task service whether it is serving.
+	Ping(context.Context) error
+	Close() error
+}
+
+type grpcClients struct {
+	// dialOpts are shared by every connection to another service
+	dialOpts []grpc.DialOption
 
+	taskConn    *grpc.ClientConn
+	taskService task_service.TaskServiceClient
+}
+
+// NewGrpcClients sets up the connections to other services. They connect in
+// the background and reconnect on their own, so a service that is down at
+// startup only fails the calls made to it; Close releases them.
 func NewGrpcClients(cfg config.Config) (ServiceManagerI, error) {
+	opts, err := DialOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceConfig, err := taskServiceConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	taskConn, err := grpc.NewClient(
+		cfg.TaskServiceHost+cfg.TaskGRPCPort,
+		append(opts, grpc.WithDefaultServiceConfig(serviceConfig))...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("task service client: %w", err)
+	}
+	taskConn.Connect()
+
+	return &grpcClients{
+		dialOpts:    opts,
+		taskConn:    taskConn,
+		taskService: task_service.NewTaskServiceClient(taskConn),
+	}, nil
+}
+
+// DialOptions are the transport, keepalive and tracing options for calls to
+// other services, TLS when GRPC_CLIENT_TLS is set.
+func DialOptions(cfg config.Config) ([]grpc.DialOption, error) {
+	creds, err := tlsutil.ClientCredentials(cfg, cfg.GRPCClientTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                cfg.GRPCKeepaliveTime,
+			Timeout:             cfg.GRPCKeepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}, nil
+}
+
+// taskServiceConfig balances calls over every address the task service
+// resolves to, skipping replicas whose health service reports them as not
+// serving, and sets the deadline and retries of its methods.
+func taskServiceConfig(cfg config.Config) (string, error) {
+	method := map[string]interface{}{
+		"name":    []map[string]string{{"service": task_service.TaskService_ServiceDesc.ServiceName}},
+		"timeout": fmt.Sprintf("%gs", cfg.TaskServiceTimeout.Seconds()),
+	}
+	if cfg.TaskServiceMaxAttempts > 1 {
+		method["retryPolicy"] = map[string]interface{}{
+			"maxAttempts":          cfg.TaskServiceMaxAttempts,
+			"initialBackoff":       "0.1s",
+			"maxBackoff":           "1s",
+			"backoffMultiplier":    2,
+			"retryableStatusCodes": []string{"UNAVAILABLE"},
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"loadBalancingConfig": []map[string]interface{}{{"round_robin": map[string]interface{}{}}},
+		"healthCheckConfig":   map[string]string{"serviceName": ""},
+		"methodConfig":        []interface{}{method},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func (g *grpcClients) TaskService() task_service.TaskServiceClient {
+	return g.taskService
+}
+
+func (g *grpcClients) Ping(ctx context.Context) error {
+	resp, err := grpc_health_v1.NewHealthClient(g.taskConn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("task service is %s", resp.Status)
+	}
+	return nil
+}
 
-	return &grpcClients{}, nil
+func (g *grpcClients) Close() error {
+	return g.taskConn.Close()
 }
@@ -0,0 +1,97 @@
+package grpc
+
+import (
+	"context"
+	"go_user_service/api"
+	"go_user_service/config"
+	"go_user_service/genproto/admin_service"
+	"go_user_service/genproto/user_service"
+	"go_user_service/pkg/tlsutil"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// gatewayHeaders are passed between HTTP and gRPC as they are, the gateway
+// would otherwise prefix or drop them.
+var gatewayHeaders = map[string]bool{
+	"authorization":     true,
+	requestIDHeader:     true,
+	correlationIDHeader: true,
+	"accept-language":   true,
+	apiKeyHeader:        true,
+}
+
+func gatewayIncomingHeader(key string) (string, bool) {
+	if gatewayHeaders[strings.ToLower(key)] {
+		return strings.ToLower(key), true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// gatewayOutgoingHeader lets only the headers meant for HTTP clients
+// through. Any other metadata the server sets stays internal.
+func gatewayOutgoingHeader(key string) (string, bool) {
+	switch key {
+	case requestIDHeader:
+		return "X-Request-Id", true
+	case retryAfterHeader:
+		return "Retry-After", true
+	}
+	return "", false
+}
+
+// gatewayOutgoingTrailer drops all trailers, the service sends none meant
+// for HTTP clients.
+func gatewayOutgoingTrailer(key string) (string, bool) {
+	return "", false
+}
+
+// NewGateway returns the HTTP/JSON front-end of the user and admin services.
+// It calls the gRPC server on cfg.ContentGRPCPort like any other client, so
+// requests go through the same interceptors. The OpenAPI specs are served
+// under /openapi/.
+func NewGateway(ctx context.Context, cfg config.Config) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(gatewayIncomingHeader),
+		runtime.WithOutgoingHeaderMatcher(gatewayOutgoingHeader),
+		runtime.WithOutgoingTrailerMatcher(gatewayOutgoingTrailer),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+	)
+
+	// the gateway has to speak TLS whenever the server does
+	creds, err := tlsutil.ClientCredentials(cfg, cfg.GRPCClientTLS || cfg.GRPCTLSCertFile != "")
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := gatewayEndpoint(cfg.ContentGRPCPort)
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+
+	if err := user_service.RegisterUserServiceHandlerFromEndpoint(ctx, mux, endpoint, opts); err != nil {
+		return nil, err
+	}
+	if err := admin_service.RegisterAdminServiceHandlerFromEndpoint(ctx, mux, endpoint, opts); err != nil {
+		return nil, err
+	}
+
+	root := http.NewServeMux()
+	root.Handle("/openapi/", http.FileServer(http.FS(api.OpenAPI)))
+	root.Handle("/", mux)
+	return root, nil
+}
+
+// gatewayEndpoint turns a listen address such as ":8081" into one that can
+// be dialled.
+func gatewayEndpoint(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "localhost" + addr
+	}
+	return addr
+}
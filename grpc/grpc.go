@@ -3,23 +3,57 @@ package grpc
 import (
 	"go_user_service/config"
 	"go_user_service/genproto/admin_service"
+	"go_user_service/genproto/task_service"
 	"go_user_service/genproto/user_service"
+	"go_user_service/pkg/logger"
 
 	"go_user_service/grpc/client"
 	"go_user_service/grpc/service"
+	"go_user_service/pkg/metrics"
 	"go_user_service/storage"
+	"time"
 
-	"github.com/saidamir98/udevs_pkg/logger"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 )
 
-func SetUpServer(cfg config.Config, log logger.LoggerI, strg storage.StorageI, srvc client.ServiceManagerI, redis storage.IRedisStorage) (grpcServer *grpc.Server) {
+func SetUpServer(cfg config.Config, log logger.LoggerI, strg storage.StorageI, srvc client.ServiceManagerI, redis storage.IRedisStorage, health *Health, limiter *RateLimiter, creds credentials.TransportCredentials) (grpcServer *grpc.Server) {
 
-	grpcServer = grpc.NewServer()
+	unary := []grpc.UnaryServerInterceptor{metrics.UnaryServerInterceptor(), logUnary(log)}
+	stream := []grpc.StreamServerInterceptor{metrics.StreamServerInterceptor(), logStream(log)}
+	if limiter != nil {
+		unary = append(unary, limiter.Unary())
+		stream = append(stream, limiter.Stream())
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		// other replicas keep their connections alive with GRPC_KEEPALIVE_TIME
+		// pings, the default policy would close them for pinging too often
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	grpcServer = grpc.NewServer(opts...)
 
 	admin_service.RegisterAdminServiceServer(grpcServer, service.NewAdminService(cfg, log, strg, srvc, redis))
 	user_service.RegisterUserServiceServer(grpcServer, service.NewUserService(cfg, log, strg, srvc, redis))
+	if cfg.TaskServiceEnabled {
+		task_service.RegisterTaskServiceServer(grpcServer, service.NewTaskService(cfg, log, strg, srvc, redis))
+	}
+
+	grpc_health_v1.RegisterHealthServer(grpcServer, health)
 
 	reflection.Register(grpcServer)
 	return
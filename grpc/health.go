@@ -0,0 +1,136 @@
+package grpc
+
+import (
+	"context"
+	"go_user_service/config"
+	"go_user_service/genproto/admin_service"
+	"go_user_service/genproto/task_service"
+	"go_user_service/genproto/user_service"
+	"go_user_service/grpc/client"
+	"go_user_service/pkg/logger"
+	"go_user_service/storage"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// LivenessService is SERVING for as long as the process can answer at
+	// all, so a liveness probe only restarts a hung server.
+	LivenessService = "liveness"
+	// ReadinessService is SERVING while postgres and redis are reachable,
+	// and the task service when TASK_SERVICE_REQUIRED is set and it isn't
+	// hosted here. The empty service name reports the same status.
+	ReadinessService = "readiness"
+)
+
+// Health serves grpc_health_v1 and keeps its statuses in line with periodic
+// postgres and redis pings. Every registered service depends on both, so
+// they all share the readiness status.
+type Health struct {
+	*health.Server
+
+	log      logger.LoggerI
+	strg     storage.StorageI
+	redis    storage.IRedisStorage
+	services client.ServiceManagerI // nil unless TASK_SERVICE_REQUIRED and the task service runs elsewhere
+	tasks    bool                   // TASK_SERVICE_ENABLED
+	interval time.Duration
+	timeout  time.Duration
+
+	mu    sync.Mutex
+	ready *bool
+}
+
+func NewHealth(cfg config.Config, log logger.LoggerI, strg storage.StorageI, redis storage.IRedisStorage, srvc client.ServiceManagerI) *Health {
+	h := &Health{
+		Server:   health.NewServer(),
+		log:      log,
+		strg:     strg,
+		redis:    redis,
+		interval: cfg.HealthCheckInterval,
+		timeout:  cfg.HealthCheckTimeout,
+		tasks:    cfg.TaskServiceEnabled,
+	}
+	// a task service hosted here is ready when this server is, pinging it
+	// would wait on the status this probe sets
+	if cfg.TaskServiceRequired && !cfg.TaskServiceEnabled {
+		h.services = srvc
+	}
+
+	h.SetServingStatus(LivenessService, grpc_health_v1.HealthCheckResponse_SERVING)
+	h.setReadiness(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	return h
+}
+
+func (h *Health) setReadiness(status grpc_health_v1.HealthCheckResponse_ServingStatus) {
+	names := []string{
+		"",
+		ReadinessService,
+		user_service.UserService_ServiceDesc.ServiceName,
+		admin_service.AdminService_ServiceDesc.ServiceName,
+	}
+	if h.tasks {
+		names = append(names, task_service.TaskService_ServiceDesc.ServiceName)
+	}
+	for _, name := range names {
+		h.SetServingStatus(name, status)
+	}
+}
+
+// Run checks the dependencies right away and then every interval until ctx
+// is cancelled.
+func (h *Health) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		h.Probe(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Probe pings postgres, redis and the required services once and updates the statuses, logging
+// only when readiness changes.
+func (h *Health) Probe(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	var failed []logger.Field
+	if err := h.strg.Ping(ctx); err != nil {
+		failed = append(failed, logger.String("postgres", err.Error()))
+	}
+	if err := h.redis.Ping(ctx); err != nil {
+		failed = append(failed, logger.String("redis", err.Error()))
+	}
+	if h.services != nil {
+		if err := h.services.Ping(ctx); err != nil {
+			failed = append(failed, logger.String("task_service", err.Error()))
+		}
+	}
+	ready := len(failed) == 0
+
+	h.mu.Lock()
+	changed := h.ready == nil || *h.ready != ready
+	h.ready = &ready
+	h.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	if ready {
+		h.log.Info("---HealthCheck--->>> ready")
+		h.setReadiness(grpc_health_v1.HealthCheckResponse_SERVING)
+	} else {
+		h.log.Error("---HealthCheck--->>> not ready", failed...)
+		h.setReadiness(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	}
+}
@@ -0,0 +1,67 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"go_user_service/config"
+	"go_user_service/genproto/task_service"
+	"go_user_service/pkg/logger"
+	"go_user_service/storage/memory"
+	"testing"
+
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// downServices is a task service that never answers its health check.
+type downServices struct {
+	pings int
+}
+
+func (d *downServices) TaskService() task_service.TaskServiceClient { return nil }
+
+func (d *downServices) Ping(ctx context.Context) error {
+	d.pings++
+	return errors.New("task service not serving")
+}
+
+func (d *downServices) Close() error { return nil }
+
+func readiness(t *testing.T, h *Health) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	resp, err := h.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: ""})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	return resp.Status
+}
+
+func TestProbeRequiredTaskService(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		hosted    bool
+		wantPings int
+		want      grpc_health_v1.HealthCheckResponse_ServingStatus
+	}{
+		// the client dials this server, whose status waits on the probe
+		{name: "hosted here", hosted: true, wantPings: 0, want: grpc_health_v1.HealthCheckResponse_SERVING},
+		{name: "external", hosted: false, wantPings: 1, want: grpc_health_v1.HealthCheckResponse_NOT_SERVING},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Load()
+			cfg.TaskServiceEnabled = tt.hosted
+			cfg.TaskServiceRequired = true
+			redis := memory.NewRedis(cfg)
+			services := &downServices{}
+
+			h := NewHealth(cfg, logger.Nop(), memory.New(redis), redis, services)
+			h.Probe(context.Background())
+
+			if got := readiness(t, h); got != tt.want {
+				t.Fatalf("readiness is %s, want %s", got, tt.want)
+			}
+			if services.pings != tt.wantPings {
+				t.Fatalf("task service was pinged %d times, want %d", services.pings, tt.wantPings)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package grpc
+
+import (
+	"context"
+	"go_user_service/pkg/logger"
+	"go_user_service/pkg/tracing"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	requestIDHeader     = "x-request-id"
+	correlationIDHeader = "x-correlation-id"
+)
+
+// requestID returns the id the caller sent in x-request-id or
+// x-correlation-id, or a new one.
+func requestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, key := range []string{requestIDHeader, correlationIDHeader} {
+		if vals := md.Get(key); len(vals) > 0 && vals[0] != "" {
+			return vals[0]
+		}
+	}
+	return uuid.NewString()
+}
+
+// withRequestLogger attaches a logger carrying the request id and trace id
+// to ctx, so every line written for the RPC can be grouped together.
+func withRequestLogger(ctx context.Context, log logger.LoggerI, method, id string) context.Context {
+	fields := append([]logger.Field{
+		logger.String("request_id", id),
+		logger.String("method", method),
+	}, tracing.LogFields(ctx)...)
+
+	return logger.NewContext(ctx, log.With(fields...))
+}
+
+// logRPC writes one line per finished RPC, so a slow or failed call can be
+// looked up by its request id or in the tracing backend.
+func logRPC(ctx context.Context, method string, start time.Time, err error) {
+	// probes would drown everything else out
+	if err == nil && strings.HasPrefix(method, "/grpc.health.v1.Health/") {
+		return
+	}
+
+	log := logger.FromContext(ctx, nil)
+	fields := []logger.Field{
+		logger.String("code", status.Code(err).String()),
+		logger.Duration("duration", time.Since(start)),
+	}
+
+	if err != nil {
+		log.Error("---RPC--->>>", append(fields, logger.Error(err))...)
+		return
+	}
+	log.Info("---RPC--->>>", fields...)
+}
+
+func logUnary(log logger.LoggerI) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		id := requestID(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, id))
+		ctx = withRequestLogger(ctx, log, info.FullMethod, id)
+
+		resp, err := handler(ctx, req)
+		logRPC(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// loggedStream hands the request logger to stream handlers.
+type loggedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s loggedStream) Context() context.Context {
+	return s.ctx
+}
+
+func logStream(log logger.LoggerI) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+
+		id := requestID(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(requestIDHeader, id))
+		ctx := withRequestLogger(ss.Context(), log, info.FullMethod, id)
+
+		err := handler(srv, loggedStream{ServerStream: ss, ctx: ctx})
+		logRPC(ctx, info.FullMethod, start, err)
+		return err
+	}
+}
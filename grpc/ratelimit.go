@@ -0,0 +1,229 @@
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go_user_service/config"
+	"go_user_service/pkg/jwt"
+	"go_user_service/pkg/logger"
+	"go_user_service/pkg/metrics"
+	"go_user_service/storage"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	apiKeyHeader       = "x-api-key"
+	retryAfterHeader   = "retry-after"
+	forwardedForHeader = "x-forwarded-for"
+)
+
+type rateRule struct {
+	// name is the RATE_LIMITS item the rule came from, methods matched by
+	// the same item share one bucket per caller
+	name   string
+	limit  int
+	period time.Duration
+}
+
+// RateLimiter rejects calls with ResourceExhausted once a caller used up the
+// quota of the method. Callers are told apart by the user in their bearer
+// token, then by a known API key and last by IP address. Buckets live in
+// redis, so the limits hold across replicas.
+type RateLimiter struct {
+	log     logger.LoggerI
+	limits  storage.RateLimiterI
+	rules   map[string]rateRule
+	apiKeys map[string]bool
+}
+
+// NewRateLimiter parses cfg.RateLimits. It returns nil when no limits are
+// configured.
+func NewRateLimiter(cfg config.Config, log logger.LoggerI, redis storage.IRedisStorage) (*RateLimiter, error) {
+	if len(cfg.RateLimits) == 0 {
+		return nil, nil
+	}
+
+	r := &RateLimiter{
+		log:     log,
+		limits:  redis.RateLimiter(),
+		rules:   make(map[string]rateRule, len(cfg.RateLimits)),
+		apiKeys: make(map[string]bool, len(cfg.APIKeys)),
+	}
+
+	for _, item := range cfg.RateLimits {
+		rule, err := parseRateRule(item)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := r.rules[rule.name]; ok {
+			return nil, fmt.Errorf("rate limit for %q is set twice", rule.name)
+		}
+		r.rules[rule.name] = rule
+	}
+
+	for _, key := range cfg.APIKeys {
+		r.apiKeys[hashAPIKey(key)] = true
+	}
+
+	return r, nil
+}
+
+// parseRateRule parses "<method>=<limit>/<period>", e.g. "UserService/Login=10/1m".
+func parseRateRule(item string) (rateRule, error) {
+	name, spec, ok := strings.Cut(item, "=")
+	if !ok {
+		return rateRule{}, fmt.Errorf("invalid rate limit %q, want <method>=<limit>/<period>", item)
+	}
+	limit, period, ok := strings.Cut(spec, "/")
+	if !ok {
+		return rateRule{}, fmt.Errorf("invalid rate limit %q, want <method>=<limit>/<period>", item)
+	}
+
+	rule := rateRule{name: strings.TrimSpace(name)}
+
+	var err error
+	if rule.limit, err = strconv.Atoi(strings.TrimSpace(limit)); err != nil || rule.limit <= 0 {
+		return rateRule{}, fmt.Errorf("invalid rate limit %q, limit must be a positive number", item)
+	}
+	if rule.period, err = time.ParseDuration(strings.TrimSpace(period)); err != nil || rule.period <= 0 {
+		return rateRule{}, fmt.Errorf("invalid rate limit %q, period must be a positive duration", item)
+	}
+	return rule, nil
+}
+
+// rule returns the most specific rule for a method such as
+// "/user_service_go.UserService/Login".
+func (r *RateLimiter) rule(method string) (rateRule, bool) {
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	service = service[strings.LastIndex(service, ".")+1:]
+
+	for _, key := range []string{method, service + "/" + name, service + "/*", "*"} {
+		if rule, ok := r.rules[key]; ok {
+			return rule, true
+		}
+	}
+	return rateRule{}, false
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// caller names who is calling, for the bucket key.
+func (r *RateLimiter) caller(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if vals := md.Get("authorization"); len(vals) > 0 {
+		claims, err := jwt.ExtractClaims(strings.TrimPrefix(vals[0], "Bearer "))
+		if err == nil {
+			if id, ok := jwt.UserID(claims); ok {
+				return "user:" + id
+			}
+		}
+	}
+
+	if vals := md.Get(apiKeyHeader); len(vals) > 0 {
+		if hash := hashAPIKey(vals[0]); r.apiKeys[hash] {
+			return "key:" + hash
+		}
+	}
+
+	return "ip:" + peerIP(ctx, md)
+}
+
+// peerIP returns the address of the client. Calls from loopback come from
+// the gateway or a local proxy, for those the last x-forwarded-for hop is
+// used, which is the address the proxy itself saw.
+func peerIP(ctx context.Context, md metadata.MD) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		if vals := md.Get(forwardedForHeader); len(vals) > 0 {
+			hops := strings.Split(vals[len(vals)-1], ",")
+			if last := strings.TrimSpace(hops[len(hops)-1]); last != "" {
+				return last
+			}
+		}
+	}
+	return host
+}
+
+// allow takes a token for the call. It returns the ResourceExhausted error
+// and how long to wait when the quota is used up. Redis failures let the
+// call through, readiness already reports them.
+func (r *RateLimiter) allow(ctx context.Context, method string) (time.Duration, error) {
+	// probes must never be throttled, whatever "*" is set to
+	if strings.HasPrefix(method, "/grpc.health.v1.Health/") {
+		return 0, nil
+	}
+
+	rule, ok := r.rule(method)
+	if !ok {
+		return 0, nil
+	}
+
+	ok, wait, err := r.limits.Take(ctx, rule.name+":"+r.caller(ctx), rule.limit, rule.period)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("---RateLimit--->>>", logger.Error(err))
+		return 0, nil
+	}
+	if ok {
+		return 0, nil
+	}
+
+	metrics.RateLimited(method)
+
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf("too many requests, retry in %s", wait.Round(time.Second)))
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}); err == nil {
+		st = detailed
+	}
+	return wait, st.Err()
+}
+
+// retryAfter is the Retry-After value for wait, in whole seconds.
+func retryAfter(wait time.Duration) metadata.MD {
+	return metadata.Pairs(retryAfterHeader, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
+
+func (r *RateLimiter) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if wait, err := r.allow(ctx, info.FullMethod); err != nil {
+			_ = grpc.SetHeader(ctx, retryAfter(wait))
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (r *RateLimiter) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if wait, err := r.allow(ss.Context(), info.FullMethod); err != nil {
+			_ = ss.SetHeader(retryAfter(wait))
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
@@ -2,20 +2,22 @@ package service
 
 import (
 	"context"
-	"errors"
-	"fmt"
 	"go_user_service/config"
 	"go_user_service/genproto/admin_service"
-	"go_user_service/pkg"
+	"go_user_service/genproto/user_service"
+	"go_user_service/pkg/export"
 	"go_user_service/pkg/hash"
 	"go_user_service/pkg/jwt"
-	"go_user_service/pkg/smtp"
-	"time"
+	"go_user_service/pkg/logger"
+	"go_user_service/pkg/mailer"
+	"go_user_service/pkg/metrics"
+	"go_user_service/pkg/otp"
+	"io"
+	"strings"
 
 	"go_user_service/grpc/client"
 	"go_user_service/storage"
 
-	"github.com/saidamir98/udevs_pkg/logger"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -39,11 +41,11 @@ func NewAdminService(cfg config.Config, log logger.LoggerI, strg storage.Storage
 
 func (f *AdminService) Create(ctx context.Context, req *admin_service.CreateAdmin) (*admin_service.GetAdmin, error) {
 
-	f.log.Info("---CreateAdmin--->>>", logger.Any("req", req))
+	logger.FromContext(ctx, f.log).Info("---CreateAdmin--->>>", logger.Any("req", req))
 
 	resp, err := f.strg.Admin().Create(ctx, req)
 	if err != nil {
-		f.log.Error("---CreateAdmin--->>>", logger.Error(err))
+		logger.FromContext(ctx, f.log).Error("---CreateAdmin--->>>", logger.Error(err))
 		return &admin_service.GetAdmin{}, err
 	}
 
@@ -51,11 +53,11 @@ func (f *AdminService) Create(ctx context.Context, req *admin_service.CreateAdmi
 }
 func (f *AdminService) Update(ctx context.Context, req *admin_service.UpdateAdmin) (*admin_service.GetAdmin, error) {
 
-	f.log.Info("---UpdateAdmin--->>>", logger.Any("req", req))
+	logger.FromContext(ctx, f.log).Info("---UpdateAdmin--->>>", logger.Any("req", req))
 
 	resp, err := f.strg.Admin().Update(ctx, req)
 	if err != nil {
-		f.log.Error("---UpdateAdmin--->>>", logger.Error(err))
+		logger.FromContext(ctx, f.log).Error("---UpdateAdmin--->>>", logger.Error(err))
 		return &admin_service.GetAdmin{}, err
 	}
 
@@ -63,11 +65,11 @@ func (f *AdminService) Update(ctx context.Context, req *admin_service.UpdateAdmi
 }
 
 func (f *AdminService) GetList(ctx context.Context, req *admin_service.GetListAdminRequest) (*admin_service.GetListAdminResponse, error) {
-	f.log.Info("---GetListAdmin--->>>", logger.Any("req", req))
+	logger.FromContext(ctx, f.log).Info("---GetListAdmin--->>>", logger.Any("req", req))
 
 	resp, err := f.strg.Admin().GetAll(ctx, req)
 	if err != nil {
-		f.log.Error("---GetListAdmin--->>>", logger.Error(err))
+		logger.FromContext(ctx, f.log).Error("---GetListAdmin--->>>", logger.Error(err))
 		return &admin_service.GetListAdminResponse{}, err
 	}
 
@@ -75,11 +77,11 @@ func (f *AdminService) GetList(ctx context.Context, req *admin_service.GetListAd
 }
 
 func (f *AdminService) GetByID(ctx context.Context, id *admin_service.AdminPrimaryKey) (*admin_service.GetAdmin, error) {
-	f.log.Info("---GetAdmin--->>>", logger.Any("req", id))
+	logger.FromContext(ctx, f.log).Info("---GetAdmin--->>>", logger.Any("req", id))
 
 	resp, err := f.strg.Admin().GetById(ctx, id)
 	if err != nil {
-		f.log.Error("---GetAdmin--->>>", logger.Error(err))
+		logger.FromContext(ctx, f.log).Error("---GetAdmin--->>>", logger.Error(err))
 		return &admin_service.GetAdmin{}, err
 	}
 
@@ -88,11 +90,11 @@ func (f *AdminService) GetByID(ctx context.Context, id *admin_service.AdminPrima
 
 func (f *AdminService) Delete(ctx context.Context, req *admin_service.AdminPrimaryKey) (*emptypb.Empty, error) {
 
-	f.log.Info("---DeleteAdmin--->>>", logger.Any("req", req))
+	logger.FromContext(ctx, f.log).Info("---DeleteAdmin--->>>", logger.Any("req", req))
 
 	_, err := f.strg.Admin().Delete(ctx, req)
 	if err != nil {
-		f.log.Error("---DeleteAdmin--->>>", logger.Error(err))
+		logger.FromContext(ctx, f.log).Error("---DeleteAdmin--->>>", logger.Error(err))
 		return &emptypb.Empty{}, err
 	}
 
@@ -100,15 +102,18 @@ func (f *AdminService) Delete(ctx context.Context, req *admin_service.AdminPrima
 }
 
 func (a *AdminService) Login(ctx context.Context, loginRequest *admin_service.AdminLoginRequest) (*admin_service.AdminLoginResponse, error) {
-	fmt.Println(" loginRequest.Login: ", loginRequest.UserLogin)
+	logger.FromContext(ctx, a.log).Info("---AdminLogin--->>>", logger.String("user_login", loginRequest.UserLogin))
+
 	admin, err := a.strg.Admin().GetByLogin(ctx, loginRequest.UserLogin)
 	if err != nil {
-		a.log.Error("error while getting admin credentials by login", logger.Error(err))
+		logger.FromContext(ctx, a.log).Error("error while getting admin credentials by login", logger.Error(err))
+		metrics.Login(metrics.AccountAdmin, err)
 		return &admin_service.AdminLoginResponse{}, err
 	}
 
-	if err = hash.CompareHashAndPassword(admin.UserPassword, loginRequest.UserPassword); err != nil {
-		a.log.Error("error while comparing password", logger.Error(err))
+	if err = hash.CompareHashAndPassword(ctx, admin.UserPassword, loginRequest.UserPassword); err != nil {
+		logger.FromContext(ctx, a.log).Error("error while comparing password", logger.Error(err))
+		metrics.Login(metrics.AccountAdmin, err)
 		return &admin_service.AdminLoginResponse{}, err
 	}
 
@@ -119,10 +124,12 @@ func (a *AdminService) Login(ctx context.Context, loginRequest *admin_service.Ad
 
 	accessToken, refreshToken, err := jwt.GenJWT(m)
 	if err != nil {
-		a.log.Error("error while generating tokens for admin login", logger.Error(err))
+		logger.FromContext(ctx, a.log).Error("error while generating tokens for admin login", logger.Error(err))
 		return &admin_service.AdminLoginResponse{}, err
 	}
 
+	metrics.Login(metrics.AccountAdmin, nil)
+
 	return &admin_service.AdminLoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
@@ -130,54 +137,48 @@ func (a *AdminService) Login(ctx context.Context, loginRequest *admin_service.Ad
 }
 
 func (a *AdminService) Register(ctx context.Context, loginRequest *admin_service.AdminRegisterRequest) (*emptypb.Empty, error) {
-	fmt.Println(" loginRequest.Login: ", loginRequest.Mail)
-
-	otpCode := pkg.GenerateOTP()
-	fmt.Println(" loginRequest.Pass: ", otpCode)
-
-	msg := fmt.Sprintf("Your otp code is: %v, for registering TODO_LIST. Don't give it to anyone", otpCode)
+	logger.FromContext(ctx, a.log).Info("---AdminRegister--->>>", logger.String("mail", loginRequest.Mail))
 
-	err := a.redis.SetX(ctx, loginRequest.Mail, otpCode, time.Minute*2)
+	otpCode, err := a.redis.OTP().Issue(ctx, otpPurposeAdminRegister, loginRequest.Mail, registerOTPTTL)
 	if err != nil {
-		a.log.Error("error while setting otpCode to redis admin register", logger.Error(err))
+		logger.FromContext(ctx, a.log).Error("error while issuing otp code for admin register", logger.Error(err))
 		return &emptypb.Empty{}, err
 	}
 
-	err = smtp.SendMail(loginRequest.Mail, msg)
+	err = enqueueCode(ctx, a.strg, mailer.RegisterOTP, loginRequest.Mail, otpCode, registerOTPTTL)
 	if err != nil {
-		a.log.Error("error while sending otp code to admin register", logger.Error(err))
+		logger.FromContext(ctx, a.log).Error("error while queueing otp email for admin register", logger.Error(err))
 		return &emptypb.Empty{}, err
 	}
+	metrics.RegistrationStarted(metrics.AccountAdmin)
+
 	return &emptypb.Empty{}, nil
 }
 
 func (a *AdminService) RegisterConfirm(ctx context.Context, req *admin_service.AdminRegisterConfRequest) (*admin_service.AdminLoginResponse, error) {
 	resp := &admin_service.AdminLoginResponse{}
 
-	otp, err := a.redis.Get(ctx, req.Mail)
+	err := a.redis.OTP().Verify(ctx, otpPurposeAdminRegister, req.Mail, req.Otp)
 	if err != nil {
-		a.log.Error("error while getting otp code for admin register confirm", logger.Error(err))
+		logger.FromContext(ctx, a.log).Error("error while verifying otp code for admin register confirm", logger.Error(err))
 		return resp, err
 	}
-	if req.Otp != otp {
-		a.log.Error("incorrect otp code for admin register confirm", logger.Error(err))
-		return resp, errors.New("incorrect otp code")
-	}
 	req.Admin[0].Email = req.Mail
 
 	id, err := a.strg.Admin().Create(ctx, req.Admin[0])
 	if err != nil {
-		a.log.Error("error while creating admin", logger.Error(err))
+		logger.FromContext(ctx, a.log).Error("error while creating admin", logger.Error(err))
 		return resp, err
 	}
+	metrics.RegistrationConfirmed(metrics.AccountAdmin)
 	var m = make(map[interface{}]interface{})
 
-	m["user_id"] = id
+	m["user_id"] = id.Id
 	m["user_role"] = config.ADMIN_ROLE
 
 	accessToken, refreshToken, err := jwt.GenJWT(m)
 	if err != nil {
-		a.log.Error("error while generating tokens for admin register confirm", logger.Error(err))
+		logger.FromContext(ctx, a.log).Error("error while generating tokens for admin register confirm", logger.Error(err))
 		return resp, err
 	}
 	resp.AccessToken = accessToken
@@ -187,13 +188,200 @@ func (a *AdminService) RegisterConfirm(ctx context.Context, req *admin_service.A
 }
 
 func (f *AdminService) ChangePassword(ctx context.Context, pass *admin_service.AdminChangePassword) (*admin_service.AdminChangePasswordResp, error) {
-	f.log.Info("---ChangePassword--->>>", logger.Any("req", pass))
+	logger.FromContext(ctx, f.log).Info("---ChangePassword--->>>", logger.Any("req", pass))
 
 	resp, err := f.strg.Admin().ChangePassword(ctx, pass)
 	if err != nil {
-		f.log.Error("---ChangePassword--->>>", logger.Error(err))
+		logger.FromContext(ctx, f.log).Error("---ChangePassword--->>>", logger.Error(err))
 		return nil, err
 	}
 
 	return resp, nil
 }
+
+func (f *AdminService) ExportUserData(req *admin_service.ExportUserDataRequest, stream admin_service.AdminService_ExportUserDataServer) error {
+	ctx := stream.Context()
+
+	logger.FromContext(ctx, f.log).Info("---ExportUserData--->>>", logger.Any("req", req))
+
+	data, err := f.strg.User().GetPersonalData(ctx, &user_service.UserPrimaryKey{Id: req.UserId})
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---ExportUserData--->>>", logger.Error(err))
+		return err
+	}
+
+	fileName, contentType, body, err := buildPersonalDataExport(data, req.UserId, req.Format)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---ExportUserData--->>>", logger.Error(err))
+		return err
+	}
+
+	return sendInChunks(body, func(chunk []byte) error {
+		return stream.Send(&admin_service.UserDataExportChunk{
+			FileName:    fileName,
+			ContentType: contentType,
+			Data:        chunk,
+		})
+	})
+}
+
+func (f *AdminService) ImportUsers(stream admin_service.AdminService_ImportUsersServer) error {
+	ctx := stream.Context()
+
+	var (
+		format string
+		dryRun bool
+		data   []byte
+	)
+	for first := true; ; first = false {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			logger.FromContext(ctx, f.log).Error("---ImportUsers--->>>", logger.Error(err))
+			return err
+		}
+		if first {
+			format, dryRun = req.Format, req.DryRun
+		}
+		data = append(data, req.Data...)
+	}
+
+	logger.FromContext(ctx, f.log).Info("---ImportUsers--->>>", logger.String("format", format), logger.Any("dry_run", dryRun), logger.Int("bytes", len(data)))
+
+	rows, err := parseImportRows(format, data)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---ImportUsers--->>>", logger.Error(err))
+		return err
+	}
+
+	resp := &admin_service.ImportUsersResponse{
+		Total:  int64(len(rows)),
+		DryRun: dryRun,
+	}
+	results := make([]*admin_service.ImportUserResult, len(rows))
+
+	seen := make(map[string]bool, len(rows))
+	emails := make([]string, 0, len(rows))
+	for i, row := range rows {
+		results[i] = &admin_service.ImportUserResult{Row: row.row}
+		if row.err != nil {
+			results[i].Status, results[i].Error = importStatusFailed, row.err.Error()
+			continue
+		}
+		row.user.Email = strings.ToLower(strings.TrimSpace(row.user.Email))
+		results[i].Email = row.user.Email
+
+		if err := validateImportUser(row.user); err != nil {
+			results[i].Status, results[i].Error = importStatusFailed, err.Error()
+			continue
+		}
+		if seen[row.user.Email] {
+			results[i].Status, results[i].Error = importStatusFailed, "duplicate email in import"
+			continue
+		}
+		seen[row.user.Email] = true
+		emails = append(emails, row.user.Email)
+	}
+
+	existing, err := f.strg.User().ExistingEmails(ctx, emails)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---ImportUsers--->>>", logger.Error(err))
+		return err
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, email := range existing {
+		taken[strings.ToLower(email)] = true
+	}
+
+	var (
+		users   []*user_service.CreateUser
+		pending []*admin_service.ImportUserResult
+	)
+	for i, row := range rows {
+		if results[i].Status != "" {
+			continue
+		}
+		if taken[row.user.Email] {
+			results[i].Status, results[i].Error = importStatusFailed, "email already registered"
+			continue
+		}
+		results[i].Status = importStatusValid
+		users = append(users, row.user)
+		pending = append(pending, results[i])
+	}
+
+	if !dryRun && len(users) > 0 {
+		created, err := f.strg.User().CreateMany(ctx, users)
+		if err != nil {
+			logger.FromContext(ctx, f.log).Error("---ImportUsers--->>>", logger.Error(err))
+			return err
+		}
+		for i, user := range created {
+			pending[i].Status = importStatusCreated
+			pending[i].Id = user.Id
+			pending[i].UserLogin = user.UserLogin
+
+			if users[i].UserPassword != "" {
+				continue
+			}
+			token, err := otp.Token(inviteTokenLength)
+			if err != nil {
+				logger.FromContext(ctx, f.log).Error("error while generating invite token", logger.Error(err))
+				pending[i].Error = "invite token was not generated"
+				continue
+			}
+			if err := f.redis.SetX(ctx, inviteKey(token), user.Id, inviteTTL); err != nil {
+				logger.FromContext(ctx, f.log).Error("error while storing invite token", logger.Error(err))
+				pending[i].Error = "invite token was not stored"
+				continue
+			}
+			pending[i].InviteToken = token
+		}
+		resp.Created = int64(len(created))
+	}
+
+	for _, result := range results {
+		if result.Status == importStatusFailed {
+			resp.Failed++
+		}
+	}
+	resp.Results = results
+
+	return stream.SendAndClose(resp)
+}
+
+func (f *AdminService) ExportUsers(req *admin_service.ExportUsersRequest, stream admin_service.AdminService_ExportUsersServer) error {
+	ctx := stream.Context()
+
+	logger.FromContext(ctx, f.log).Info("---ExportUsers--->>>", logger.Any("req", req))
+
+	out := &chunkWriter{send: func(chunk []byte) error {
+		// the chunk buffer is reused, so it must be copied before sending
+		return stream.Send(&admin_service.ExportUsersChunk{Data: append([]byte(nil), chunk...)})
+	}}
+
+	w, err := export.NewUserWriter(req.Format, out)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---ExportUsers--->>>", logger.Error(err))
+		return err
+	}
+
+	err = f.strg.User().Stream(ctx, &user_service.GetListUserRequest{
+		Offset: req.Offset,
+		Limit:  req.Limit,
+		Search: req.Search,
+	}, w.Write)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---ExportUsers--->>>", logger.Error(err))
+		return err
+	}
+
+	if err = w.Close(); err != nil {
+		logger.FromContext(ctx, f.log).Error("---ExportUsers--->>>", logger.Error(err))
+		return err
+	}
+
+	return out.Flush()
+}
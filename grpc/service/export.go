@@ -0,0 +1,128 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_user_service/pkg/jwt"
+	"go_user_service/storage"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	exportFormatJSON = "json"
+	exportFormatZip  = "zip"
+
+	exportChunkSize = 64 * 1024
+)
+
+type personalDataExport struct {
+	UserID      string `json:"user_id"`
+	GeneratedAt string `json:"generated_at"`
+	*storage.PersonalData
+}
+
+// buildPersonalDataExport renders everything stored about the user as a
+// single file in the requested format.
+func buildPersonalDataExport(data *storage.PersonalData, userID, format string) (fileName, contentType string, body []byte, err error) {
+	export := personalDataExport{
+		UserID:       userID,
+		GeneratedAt:  time.Now().UTC().Format(time.RFC3339),
+		PersonalData: data,
+	}
+
+	doc, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	switch strings.ToLower(format) {
+	case "", exportFormatJSON:
+		return fmt.Sprintf("user_%s.json", userID), "application/json", doc, nil
+	case exportFormatZip:
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create("personal_data.json")
+		if err != nil {
+			return "", "", nil, err
+		}
+		if _, err = w.Write(doc); err != nil {
+			return "", "", nil, err
+		}
+		if err = zw.Close(); err != nil {
+			return "", "", nil, err
+		}
+		return fmt.Sprintf("user_%s.zip", userID), "application/zip", buf.Bytes(), nil
+	default:
+		return "", "", nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// sendInChunks splits body into exportChunkSize pieces and passes them to send.
+func sendInChunks(body []byte, send func([]byte) error) error {
+	for len(body) > 0 {
+		n := exportChunkSize
+		if len(body) < n {
+			n = len(body)
+		}
+		if err := send(body[:n]); err != nil {
+			return err
+		}
+		body = body[n:]
+	}
+	return nil
+}
+
+// userIDFromContext returns the user_id claim of the bearer token passed in
+// the authorization metadata.
+func userIDFromContext(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get("authorization")) == 0 {
+		return "", errors.New("authorization token is required")
+	}
+	token := strings.TrimPrefix(md.Get("authorization")[0], "Bearer ")
+
+	claims, err := jwt.ExtractClaims(token)
+	if err != nil {
+		return "", err
+	}
+
+	userID, ok := jwt.UserID(claims)
+	if !ok {
+		return "", errors.New("token has no user_id")
+	}
+	return userID, nil
+}
+
+// chunkWriter buffers writes and hands them to send in exportChunkSize pieces.
+type chunkWriter struct {
+	buf  []byte
+	send func([]byte) error
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	c.buf = append(c.buf, p...)
+	for len(c.buf) >= exportChunkSize {
+		if err := c.send(c.buf[:exportChunkSize]); err != nil {
+			return 0, err
+		}
+		c.buf = append(c.buf[:0], c.buf[exportChunkSize:]...)
+	}
+	return len(p), nil
+}
+
+// Flush sends whatever is left in the buffer.
+func (c *chunkWriter) Flush() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	err := c.send(c.buf)
+	c.buf = c.buf[:0]
+	return err
+}
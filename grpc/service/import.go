@@ -0,0 +1,159 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_user_service/genproto/user_service"
+	"go_user_service/pkg/check"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	importFormatCSV   = "csv"
+	importFormatJSONL = "jsonl"
+
+	importStatusCreated = "created"
+	importStatusValid   = "valid"
+	importStatusFailed  = "failed"
+
+	inviteTTL         = 7 * 24 * time.Hour
+	inviteTokenLength = 32
+)
+
+// inviteKey is the redis key holding the user id an invite token belongs to.
+// Only the hash of the token is stored, so the keys can't be used to sign in.
+func inviteKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "invite:" + hex.EncodeToString(sum[:])
+}
+
+// importRow is one parsed row of an import file, numbered from 1 not
+// counting the CSV header.
+type importRow struct {
+	row  int64
+	user *user_service.CreateUser
+	err  error
+}
+
+// parseImportRows reads users from CSV (with a header line) or JSON lines.
+// Rows that can't be decoded are returned with err set so they still show up
+// in the report.
+func parseImportRows(format string, data []byte) ([]importRow, error) {
+	switch strings.ToLower(format) {
+	case importFormatCSV:
+		return parseImportCSV(data)
+	case importFormatJSONL, "json":
+		return parseImportJSONL(data)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+func parseImportCSV(data []byte) ([]importRow, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New("csv header has no email column")
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []importRow
+	for n := int64(1); ; n++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rows = append(rows, importRow{row: n, err: err})
+			continue
+		}
+		password := field(record, "user_password")
+		if password == "" {
+			password = field(record, "password")
+		}
+		rows = append(rows, importRow{row: n, user: &user_service.CreateUser{
+			Birthday:     field(record, "birthday"),
+			Gender:       field(record, "gender"),
+			Fullname:     field(record, "fullname"),
+			Email:        field(record, "email"),
+			Phone:        field(record, "phone"),
+			UserPassword: password,
+		}})
+	}
+
+	return rows, nil
+}
+
+func parseImportJSONL(data []byte) ([]importRow, error) {
+	var rows []importRow
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := int64(1); sc.Scan(); {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var user user_service.CreateUser
+		if err := json.Unmarshal(line, &user); err != nil {
+			rows = append(rows, importRow{row: n, err: err})
+		} else {
+			rows = append(rows, importRow{row: n, user: &user})
+		}
+		n++
+	}
+
+	return rows, sc.Err()
+}
+
+// validateImportUser applies the same rules as the rest of the service to
+// a single imported user.
+func validateImportUser(user *user_service.CreateUser) error {
+	if user.Fullname == "" {
+		return errors.New("fullname is required")
+	}
+	if err := check.ValidateMail(user.Email); err != nil {
+		return err
+	}
+	if user.Phone != "" {
+		if err := check.ValidatePhone(user.Phone); err != nil {
+			return err
+		}
+	}
+	if user.Birthday != "" {
+		if err := check.ValidateDate(user.Birthday); err != nil {
+			return err
+		}
+	}
+	// gender is read back into a plain string, so like Create the import
+	// needs one for every row
+	if user.Gender == "" {
+		return errors.New("gender is required")
+	}
+	return check.ValidateGender(user.Gender)
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"go_user_service/outbox"
+	"go_user_service/pkg/mailer"
+	"go_user_service/storage"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// languagesFromContext returns the languages asked for in the
+// accept-language metadata, most preferred first.
+func languagesFromContext(ctx context.Context) []string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var langs []string
+	for _, header := range md.Get("accept-language") {
+		langs = append(langs, mailer.ParseAcceptLanguage(header)...)
+	}
+	return langs
+}
+
+// enqueueCode puts an email of the given kind carrying code into the outbox,
+// in the caller's language. It is sent by the outbox dispatcher, unless the
+// code has expired by then.
+func enqueueCode(ctx context.Context, strg storage.StorageI, kind mailer.Kind, to, code string, ttl time.Duration) error {
+	msg, err := outbox.NewEmail(outbox.Email{
+		Kind:      kind,
+		To:        to,
+		Langs:     languagesFromContext(ctx),
+		Code:      code,
+		Minutes:   int(ttl / time.Minute),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	return strg.Outbox().Add(ctx, msg)
+}
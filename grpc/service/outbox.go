@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go_user_service/genproto/admin_service"
+	"go_user_service/pkg/check"
+	"go_user_service/pkg/logger"
+	"go_user_service/storage"
+	"time"
+)
+
+func outboxMessageToProto(msg *storage.OutboxMessage) *admin_service.OutboxMessage {
+	out := &admin_service.OutboxMessage{
+		Id:          msg.Id,
+		Kind:        msg.Kind,
+		Status:      msg.Status,
+		Attempts:    int64(msg.Attempts),
+		LastError:   msg.LastError,
+		AvailableAt: msg.AvailableAt.Format(time.RFC3339),
+		CreatedAt:   msg.CreatedAt.Format(time.RFC3339),
+	}
+	if !msg.SentAt.IsZero() {
+		out.SentAt = msg.SentAt.Format(time.RFC3339)
+	}
+	return out
+}
+
+func (f *AdminService) ListOutbox(ctx context.Context, req *admin_service.ListOutboxRequest) (*admin_service.ListOutboxResponse, error) {
+	logger.FromContext(ctx, f.log).Info("---ListOutbox--->>>", logger.Any("req", req))
+
+	msgs, count, err := f.strg.Outbox().List(ctx, &storage.OutboxListRequest{
+		Offset: req.Offset,
+		Limit:  req.Limit,
+		Status: req.Status,
+		Kind:   req.Kind,
+	})
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---ListOutbox--->>>", logger.Error(err))
+		return &admin_service.ListOutboxResponse{}, err
+	}
+
+	resp := &admin_service.ListOutboxResponse{Count: count}
+	for _, msg := range msgs {
+		resp.Messages = append(resp.Messages, outboxMessageToProto(msg))
+	}
+
+	return resp, nil
+}
+
+func (f *AdminService) RetryOutbox(ctx context.Context, req *admin_service.RetryOutboxRequest) (*admin_service.RetryOutboxResponse, error) {
+	logger.FromContext(ctx, f.log).Info("---RetryOutbox--->>>", logger.Any("req", req))
+
+	for _, id := range req.Ids {
+		if !check.IsValidUUID(id) {
+			return &admin_service.RetryOutboxResponse{}, fmt.Errorf("invalid outbox message id %q", id)
+		}
+	}
+
+	retried, err := f.strg.Outbox().Retry(ctx, req.Ids)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---RetryOutbox--->>>", logger.Error(err))
+		return &admin_service.RetryOutboxResponse{}, err
+	}
+
+	return &admin_service.RetryOutboxResponse{Retried: retried}, nil
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go_user_service/config"
+	"go_user_service/genproto/task_service"
+	"go_user_service/genproto/user_service"
+	"go_user_service/grpc/client"
+	"go_user_service/pkg/check"
+	"go_user_service/pkg/logger"
+	"go_user_service/storage"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	taskStatusNew        = "new"
+	taskStatusInProgress = "in_progress"
+	taskStatusDone       = "done"
+	taskStatusCancelled  = "cancelled"
+)
+
+// validateTaskStatus accepts the statuses a task can be in.
+func validateTaskStatus(status string) error {
+	switch status {
+	case taskStatusNew, taskStatusInProgress, taskStatusDone, taskStatusCancelled:
+		return nil
+	}
+	return errors.New("task_status must be new, in_progress, done or cancelled")
+}
+
+type TaskService struct {
+	cfg      config.Config
+	log      logger.LoggerI
+	strg     storage.StorageI
+	services client.ServiceManagerI
+	redis    storage.IRedisStorage
+}
+
+func NewTaskService(cfg config.Config, log logger.LoggerI, strg storage.StorageI, srvs client.ServiceManagerI, redis storage.IRedisStorage) *TaskService {
+	return &TaskService{
+		cfg:      cfg,
+		log:      log,
+		strg:     strg,
+		services: srvs,
+		redis:    redis,
+	}
+}
+
+// checkOwner makes sure tasks are only given to active users.
+func (f *TaskService) checkOwner(ctx context.Context, userID string) error {
+	if !check.IsValidUUID(userID) {
+		return errors.New("invalid user_id")
+	}
+	resp, err := f.strg.User().Check(ctx, &user_service.UserPrimaryKey{Id: userID})
+	if err != nil {
+		return err
+	}
+	if !resp.Check {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (f *TaskService) Create(ctx context.Context, req *task_service.CreateTask) (*task_service.GetTask, error) {
+
+	logger.FromContext(ctx, f.log).Info("---CreateTask--->>>", logger.Any("req", req))
+
+	if req.Title == "" {
+		return &task_service.GetTask{}, errors.New("title is required")
+	}
+	if err := f.checkOwner(ctx, req.UserId); err != nil {
+		logger.FromContext(ctx, f.log).Error("---CreateTask--->>>", logger.Error(err))
+		return &task_service.GetTask{}, err
+	}
+	if req.TaskStatus == "" {
+		req.TaskStatus = taskStatusNew
+	}
+	if err := validateTaskStatus(req.TaskStatus); err != nil {
+		return &task_service.GetTask{}, err
+	}
+
+	resp, err := f.strg.Task().Create(ctx, req)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---CreateTask--->>>", logger.Error(err))
+		return &task_service.GetTask{}, err
+	}
+
+	return resp, nil
+}
+
+func (f *TaskService) GetByID(ctx context.Context, id *task_service.TaskPrimaryKey) (*task_service.GetTask, error) {
+	logger.FromContext(ctx, f.log).Info("---GetTask--->>>", logger.Any("req", id))
+
+	if !check.IsValidUUID(id.Id) {
+		return &task_service.GetTask{}, errors.New("invalid id")
+	}
+
+	resp, err := f.strg.Task().GetById(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---GetTask--->>>", logger.Error(err))
+		return &task_service.GetTask{}, err
+	}
+
+	return resp, nil
+}
+
+func (f *TaskService) GetByExternalId(ctx context.Context, id *task_service.TaskPrimaryKey) (*task_service.GetTask, error) {
+	logger.FromContext(ctx, f.log).Info("---GetTaskByExternalId--->>>", logger.Any("req", id))
+
+	resp, err := f.strg.Task().GetByExternalId(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---GetTaskByExternalId--->>>", logger.Error(err))
+		return &task_service.GetTask{}, err
+	}
+
+	return resp, nil
+}
+
+func (f *TaskService) Update(ctx context.Context, req *task_service.UpdateTask) (*task_service.GetTask, error) {
+
+	logger.FromContext(ctx, f.log).Info("---UpdateTask--->>>", logger.Any("req", req))
+
+	if !check.IsValidUUID(req.Id) {
+		return &task_service.GetTask{}, errors.New("invalid id")
+	}
+	if req.Title == "" {
+		return &task_service.GetTask{}, errors.New("title is required")
+	}
+	// task_description is a number in UpdateTask and can't carry the text,
+	// so it is refused rather than silently dropped
+	if req.TaskDescription != 0 {
+		return &task_service.GetTask{}, errors.New("task_description can't be changed with Update")
+	}
+	if err := f.checkOwner(ctx, req.UserId); err != nil {
+		logger.FromContext(ctx, f.log).Error("---UpdateTask--->>>", logger.Error(err))
+		return &task_service.GetTask{}, err
+	}
+
+	resp, err := f.strg.Task().Update(ctx, req)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---UpdateTask--->>>", logger.Error(err))
+		return &task_service.GetTask{}, err
+	}
+
+	return resp, nil
+}
+
+func (f *TaskService) ChangeStatus(ctx context.Context, req *task_service.TaskChangeStatus) (*task_service.TaskChangeStatusResp, error) {
+
+	logger.FromContext(ctx, f.log).Info("---ChangeTaskStatus--->>>", logger.Any("req", req))
+
+	if !check.IsValidUUID(req.TaskId) {
+		return &task_service.TaskChangeStatusResp{}, errors.New("invalid task_id")
+	}
+	if req.NewStatus == "" {
+		return &task_service.TaskChangeStatusResp{}, errors.New("new_status is required")
+	}
+	if err := validateTaskStatus(req.NewStatus); err != nil {
+		return &task_service.TaskChangeStatusResp{}, err
+	}
+
+	old, err := f.strg.Task().ChangeStatus(ctx, req)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---ChangeTaskStatus--->>>", logger.Error(err))
+		return &task_service.TaskChangeStatusResp{}, err
+	}
+
+	return &task_service.TaskChangeStatusResp{
+		Comment: "status changed from " + old + " to " + req.NewStatus,
+	}, nil
+}
+
+func (f *TaskService) Delete(ctx context.Context, req *task_service.TaskPrimaryKey) (*emptypb.Empty, error) {
+
+	logger.FromContext(ctx, f.log).Info("---DeleteTask--->>>", logger.Any("req", req))
+
+	if !check.IsValidUUID(req.Id) {
+		return &emptypb.Empty{}, errors.New("invalid id")
+	}
+
+	_, err := f.strg.Task().Delete(ctx, req)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---DeleteTask--->>>", logger.Error(err))
+		return &emptypb.Empty{}, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (f *TaskService) GetList(ctx context.Context, req *task_service.GetListTaskRequest) (*task_service.GetListTaskResponse, error) {
+	logger.FromContext(ctx, f.log).Info("---GetListTask--->>>", logger.Any("req", req))
+
+	resp, err := f.strg.Task().GetAll(ctx, req)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---GetListTask--->>>", logger.Error(err))
+		return &task_service.GetListTaskResponse{}, err
+	}
+
+	return resp, nil
+}
+
+func (f *TaskService) DeleteByOwner(ctx context.Context, req *task_service.TaskOwner) (*task_service.TaskOwnerResp, error) {
+	logger.FromContext(ctx, f.log).Info("---DeleteTasksByOwner--->>>", logger.Any("req", req))
+
+	affected, err := f.strg.Task().DeleteByOwner(ctx, req.OwnerId)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---DeleteTasksByOwner--->>>", logger.Error(err))
+		return &task_service.TaskOwnerResp{}, err
+	}
+
+	return &task_service.TaskOwnerResp{Affected: affected}, nil
+}
+
+func (f *TaskService) RestoreByOwner(ctx context.Context, req *task_service.TaskOwner) (*task_service.TaskOwnerResp, error) {
+	logger.FromContext(ctx, f.log).Info("---RestoreTasksByOwner--->>>", logger.Any("req", req))
+
+	affected, err := f.strg.Task().RestoreByOwner(ctx, req.OwnerId)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---RestoreTasksByOwner--->>>", logger.Error(err))
+		return &task_service.TaskOwnerResp{}, err
+	}
+
+	return &task_service.TaskOwnerResp{Affected: affected}, nil
+}
+
+func (f *TaskService) PurgeByOwner(ctx context.Context, req *task_service.TaskOwner) (*task_service.TaskOwnerResp, error) {
+	logger.FromContext(ctx, f.log).Info("---PurgeTasksByOwner--->>>", logger.Any("req", req))
+
+	affected, err := f.strg.Task().PurgeByOwner(ctx, req.OwnerId)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---PurgeTasksByOwner--->>>", logger.Error(err))
+		return &task_service.TaskOwnerResp{}, err
+	}
+
+	return &task_service.TaskOwnerResp{Affected: affected}, nil
+}
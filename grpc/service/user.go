@@ -3,22 +3,30 @@ package service
 import (
 	"context"
 	"errors"
-	"fmt"
 	"go_user_service/config"
 	"go_user_service/genproto/user_service"
-	"go_user_service/pkg"
 	"go_user_service/pkg/hash"
 	"go_user_service/pkg/jwt"
-	"go_user_service/pkg/smtp"
+	"go_user_service/pkg/logger"
+	"go_user_service/pkg/mailer"
+	"go_user_service/pkg/metrics"
 	"time"
 
 	"go_user_service/grpc/client"
 	"go_user_service/storage"
 
-	"github.com/saidamir98/udevs_pkg/logger"
+	"github.com/jackc/pgx/v4"
+	"github.com/redis/go-redis/v9"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
+const (
+	otpPurposeUserRegister  = "user_register"
+	otpPurposeAdminRegister = "admin_register"
+
+	registerOTPTTL = time.Minute * 2
+)
+
 type UserService struct {
 	cfg      config.Config
 	log      logger.LoggerI
@@ -39,11 +47,11 @@ func NewUserService(cfg config.Config, log logger.LoggerI, strg storage.StorageI
 
 func (f *UserService) Create(ctx context.Context, req *user_service.CreateUser) (*user_service.GetUser, error) {
 
-	f.log.Info("---CreateUser--->>>", logger.Any("req", req))
+	logger.FromContext(ctx, f.log).Info("---CreateUser--->>>", logger.Any("req", req))
 
 	resp, err := f.strg.User().Create(ctx, req)
 	if err != nil {
-		f.log.Error("---CreateUser--->>>", logger.Error(err))
+		logger.FromContext(ctx, f.log).Error("---CreateUser--->>>", logger.Error(err))
 		return &user_service.GetUser{}, err
 	}
 
@@ -51,11 +59,11 @@ func (f *UserService) Create(ctx context.Context, req *user_service.CreateUser)
 }
 func (f *UserService) Update(ctx context.Context, req *user_service.UpdateUser) (*user_service.GetUser, error) {
 
-	f.log.Info("---UpdateUser--->>>", logger.Any("req", req))
+	logger.FromContext(ctx, f.log).Info("---UpdateUser--->>>", logger.Any("req", req))
 
 	resp, err := f.strg.User().Update(ctx, req)
 	if err != nil {
-		f.log.Error("---UpdateUser--->>>", logger.Error(err))
+		logger.FromContext(ctx, f.log).Error("---UpdateUser--->>>", logger.Error(err))
 		return &user_service.GetUser{}, err
 	}
 
@@ -63,11 +71,11 @@ func (f *UserService) Update(ctx context.Context, req *user_service.UpdateUser)
 }
 
 func (f *UserService) GetList(ctx context.Context, req *user_service.GetListUserRequest) (*user_service.GetListUserResponse, error) {
-	f.log.Info("---GetListUser--->>>", logger.Any("req", req))
+	logger.FromContext(ctx, f.log).Info("---GetListUser--->>>", logger.Any("req", req))
 
 	resp, err := f.strg.User().GetAll(ctx, req)
 	if err != nil {
-		f.log.Error("---GetListUser--->>>", logger.Error(err))
+		logger.FromContext(ctx, f.log).Error("---GetListUser--->>>", logger.Error(err))
 		return &user_service.GetListUserResponse{}, err
 	}
 
@@ -75,11 +83,11 @@ func (f *UserService) GetList(ctx context.Context, req *user_service.GetListUser
 }
 
 func (f *UserService) GetByID(ctx context.Context, id *user_service.UserPrimaryKey) (*user_service.GetUser, error) {
-	f.log.Info("---GetUser--->>>", logger.Any("req", id))
+	logger.FromContext(ctx, f.log).Info("---GetUser--->>>", logger.Any("req", id))
 
 	resp, err := f.strg.User().GetById(ctx, id)
 	if err != nil {
-		f.log.Error("---GetUser--->>>", logger.Error(err))
+		logger.FromContext(ctx, f.log).Error("---GetUser--->>>", logger.Error(err))
 		return &user_service.GetUser{}, err
 	}
 
@@ -88,11 +96,37 @@ func (f *UserService) GetByID(ctx context.Context, id *user_service.UserPrimaryK
 
 func (f *UserService) Delete(ctx context.Context, req *user_service.UserPrimaryKey) (*emptypb.Empty, error) {
 
-	f.log.Info("---DeleteUser--->>>", logger.Any("req", req))
+	logger.FromContext(ctx, f.log).Info("---DeleteUser--->>>", logger.Any("req", req))
 
 	_, err := f.strg.User().Delete(ctx, req)
 	if err != nil {
-		f.log.Error("---DeleteUser--->>>", logger.Error(err))
+		logger.FromContext(ctx, f.log).Error("---DeleteUser--->>>", logger.Error(err))
+		return &emptypb.Empty{}, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (f *UserService) Restore(ctx context.Context, req *user_service.UserPrimaryKey) (*emptypb.Empty, error) {
+
+	logger.FromContext(ctx, f.log).Info("---RestoreUser--->>>", logger.Any("req", req))
+
+	_, err := f.strg.User().Restore(ctx, req)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---RestoreUser--->>>", logger.Error(err))
+		return &emptypb.Empty{}, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (f *UserService) Purge(ctx context.Context, req *user_service.UserPrimaryKey) (*emptypb.Empty, error) {
+
+	logger.FromContext(ctx, f.log).Info("---PurgeUser--->>>", logger.Any("req", req))
+
+	_, err := f.strg.User().Purge(ctx, req)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---PurgeUser--->>>", logger.Error(err))
 		return &emptypb.Empty{}, err
 	}
 
@@ -100,11 +134,11 @@ func (f *UserService) Delete(ctx context.Context, req *user_service.UserPrimaryK
 }
 
 func (f *UserService) Check(ctx context.Context, id *user_service.UserPrimaryKey) (*user_service.CheckUserResp, error) {
-	f.log.Info("---GetUser--->>>", logger.Any("req", id))
+	logger.FromContext(ctx, f.log).Info("---GetUser--->>>", logger.Any("req", id))
 
 	resp, err := f.strg.User().Check(ctx, id)
 	if err != nil {
-		f.log.Error("---GetUser--->>>", logger.Error(err))
+		logger.FromContext(ctx, f.log).Error("---GetUser--->>>", logger.Error(err))
 		return &user_service.CheckUserResp{}, err
 	}
 
@@ -112,15 +146,18 @@ func (f *UserService) Check(ctx context.Context, id *user_service.UserPrimaryKey
 }
 
 func (a *UserService) Login(ctx context.Context, loginRequest *user_service.UserLoginRequest) (*user_service.UserLoginResponse, error) {
-	fmt.Println(" loginRequest.Login: ", loginRequest.UserLogin)
+	logger.FromContext(ctx, a.log).Info("---UserLogin--->>>", logger.String("user_login", loginRequest.UserLogin))
+
 	user, err := a.strg.User().GetByLogin(ctx, loginRequest.UserLogin)
 	if err != nil {
-		a.log.Error("error while getting user credentials by login", logger.Error(err))
+		logger.FromContext(ctx, a.log).Error("error while getting user credentials by login", logger.Error(err))
+		metrics.Login(metrics.AccountUser, err)
 		return &user_service.UserLoginResponse{}, err
 	}
 
-	if err = hash.CompareHashAndPassword(user.UserPassword, loginRequest.UserPassword); err != nil {
-		a.log.Error("error while comparing password", logger.Error(err))
+	if err = hash.CompareHashAndPassword(ctx, user.UserPassword, loginRequest.UserPassword); err != nil {
+		logger.FromContext(ctx, a.log).Error("error while comparing password", logger.Error(err))
+		metrics.Login(metrics.AccountUser, err)
 		return &user_service.UserLoginResponse{}, err
 	}
 
@@ -131,10 +168,12 @@ func (a *UserService) Login(ctx context.Context, loginRequest *user_service.User
 
 	accessToken, refreshToken, err := jwt.GenJWT(m)
 	if err != nil {
-		a.log.Error("error while generating tokens for user login", logger.Error(err))
+		logger.FromContext(ctx, a.log).Error("error while generating tokens for user login", logger.Error(err))
 		return &user_service.UserLoginResponse{}, err
 	}
 
+	metrics.Login(metrics.AccountUser, nil)
+
 	return &user_service.UserLoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
@@ -142,53 +181,48 @@ func (a *UserService) Login(ctx context.Context, loginRequest *user_service.User
 }
 
 func (a *UserService) Register(ctx context.Context, loginRequest *user_service.UserRegisterRequest) (*emptypb.Empty, error) {
-	fmt.Println(" loginRequest.Login: ", loginRequest.Mail)
-
-	otpCode := pkg.GenerateOTP()
-
-	msg := fmt.Sprintf("Your otp code is: %v, for registering CRM system. Don't give it to anyone", otpCode)
+	logger.FromContext(ctx, a.log).Info("---UserRegister--->>>", logger.String("mail", loginRequest.Mail))
 
-	err := a.redis.SetX(ctx, loginRequest.Mail, otpCode, time.Minute*2)
+	otpCode, err := a.redis.OTP().Issue(ctx, otpPurposeUserRegister, loginRequest.Mail, registerOTPTTL)
 	if err != nil {
-		a.log.Error("error while setting otpCode to redis user register", logger.Error(err))
+		logger.FromContext(ctx, a.log).Error("error while issuing otp code for user register", logger.Error(err))
 		return &emptypb.Empty{}, err
 	}
 
-	err = smtp.SendMail(loginRequest.Mail, msg)
+	err = enqueueCode(ctx, a.strg, mailer.RegisterOTP, loginRequest.Mail, otpCode, registerOTPTTL)
 	if err != nil {
-		a.log.Error("error while sending otp code to user register", logger.Error(err))
+		logger.FromContext(ctx, a.log).Error("error while queueing otp email for user register", logger.Error(err))
 		return &emptypb.Empty{}, err
 	}
+	metrics.RegistrationStarted(metrics.AccountUser)
+
 	return &emptypb.Empty{}, nil
 }
 
 func (a *UserService) RegisterConfirm(ctx context.Context, req *user_service.UserRegisterConfRequest) (*user_service.UserLoginResponse, error) {
 	resp := &user_service.UserLoginResponse{}
 
-	otp, err := a.redis.Get(ctx, req.Mail)
+	err := a.redis.OTP().Verify(ctx, otpPurposeUserRegister, req.Mail, req.Otp)
 	if err != nil {
-		a.log.Error("error while getting otp code for user register confirm", logger.Error(err))
+		logger.FromContext(ctx, a.log).Error("error while verifying otp code for user register confirm", logger.Error(err))
 		return resp, err
 	}
-	if req.Otp != otp {
-		a.log.Error("incorrect otp code for user register confirm", logger.Error(err))
-		return resp, errors.New("incorrect otp code")
-	}
 	req.User[0].Email = req.Mail
 
 	id, err := a.strg.User().Create(ctx, req.User[0])
 	if err != nil {
-		a.log.Error("error while creating user", logger.Error(err))
+		logger.FromContext(ctx, a.log).Error("error while creating user", logger.Error(err))
 		return resp, err
 	}
+	metrics.RegistrationConfirmed(metrics.AccountUser)
 	var m = make(map[interface{}]interface{})
 
-	m["user_id"] = id
+	m["user_id"] = id.Id
 	m["user_role"] = config.USER_ROLE
 
 	accessToken, refreshToken, err := jwt.GenJWT(m)
 	if err != nil {
-		a.log.Error("error while generating tokens for user register confirm", logger.Error(err))
+		logger.FromContext(ctx, a.log).Error("error while generating tokens for user register confirm", logger.Error(err))
 		return resp, err
 	}
 	resp.AccessToken = accessToken
@@ -198,13 +232,85 @@ func (a *UserService) RegisterConfirm(ctx context.Context, req *user_service.Use
 }
 
 func (f *UserService) ChangePassword(ctx context.Context, pass *user_service.UserChangePassword) (*user_service.UserChangePasswordResp, error) {
-	f.log.Info("---ChangePassword--->>>", logger.Any("req", pass))
+	logger.FromContext(ctx, f.log).Info("---ChangePassword--->>>", logger.Any("req", pass))
 
 	resp, err := f.strg.User().ChangePassword(ctx, pass)
 	if err != nil {
-		f.log.Error("---ChangePassword--->>>", logger.Error(err))
+		logger.FromContext(ctx, f.log).Error("---ChangePassword--->>>", logger.Error(err))
 		return nil, err
 	}
 
 	return resp, nil
 }
+
+var errInvalidInvite = errors.New("invite token is invalid or expired")
+
+func (f *UserService) AcceptInvite(ctx context.Context, req *user_service.UserAcceptInviteRequest) (*user_service.UserChangePasswordResp, error) {
+	logger.FromContext(ctx, f.log).Info("---AcceptInvite--->>>")
+
+	if req.Token == "" {
+		return nil, errors.New("token is required")
+	}
+	if req.NewPassword == "" {
+		return nil, errors.New("new_password is required")
+	}
+
+	// the invite is deleted only after the password is saved, so a failed
+	// SetPassword leaves it usable. SetPassword itself makes it single-use:
+	// it only updates users that have no password yet
+	key := inviteKey(req.Token)
+	userID, err := f.redis.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, errInvalidInvite
+		}
+		logger.FromContext(ctx, f.log).Error("---AcceptInvite--->>>", logger.Error(err))
+		return nil, err
+	}
+
+	if err = f.strg.User().SetPassword(ctx, userID, req.NewPassword); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errInvalidInvite
+		}
+		logger.FromContext(ctx, f.log).Error("---AcceptInvite--->>>", logger.Error(err))
+		return nil, err
+	}
+
+	if err = f.redis.Del(ctx, key); err != nil {
+		logger.FromContext(ctx, f.log).Error("---AcceptInvite--->>>", logger.Error(err))
+	}
+
+	return &user_service.UserChangePasswordResp{Comment: "Password set successfully"}, nil
+}
+
+func (f *UserService) ExportMyData(req *user_service.ExportMyDataRequest, stream user_service.UserService_ExportMyDataServer) error {
+	ctx := stream.Context()
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---ExportMyData--->>>", logger.Error(err))
+		return err
+	}
+
+	logger.FromContext(ctx, f.log).Info("---ExportMyData--->>>", logger.String("user_id", userID), logger.String("format", req.Format))
+
+	data, err := f.strg.User().GetPersonalData(ctx, &user_service.UserPrimaryKey{Id: userID})
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---ExportMyData--->>>", logger.Error(err))
+		return err
+	}
+
+	fileName, contentType, body, err := buildPersonalDataExport(data, userID, req.Format)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---ExportMyData--->>>", logger.Error(err))
+		return err
+	}
+
+	return sendInChunks(body, func(chunk []byte) error {
+		return stream.Send(&user_service.DataExportChunk{
+			FileName:    fileName,
+			ContentType: contentType,
+			Data:        chunk,
+		})
+	})
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go_user_service/genproto/user_events"
+	"go_user_service/genproto/user_service"
+	"go_user_service/pkg/logger"
+	"go_user_service/storage"
+	"time"
+)
+
+const (
+	watchBatchSize = 100
+	// watchBlock is how long a single read waits for new events before the
+	// loop checks whether the client went away
+	watchBlock = 5 * time.Second
+
+	userChangeCreated  = "created"
+	userChangeUpdated  = "updated"
+	userChangeDeleted  = "deleted"
+	userChangeRestored = "restored"
+	userChangePurged   = "purged"
+)
+
+// userChangeFromEvent maps a lifecycle event to a UserChange, or returns nil
+// for events that are not about a user account.
+func userChangeFromEvent(ev *user_events.Event) *user_service.UserChange {
+	change := &user_service.UserChange{
+		EventId:    ev.Id,
+		OccurredAt: ev.OccurredAt,
+	}
+
+	switch p := ev.Payload.(type) {
+	case *user_events.Event_UserCreated:
+		change.Type = userChangeCreated
+		change.UserId = p.UserCreated.UserId
+		change.User = &user_service.GetUser{
+			Id:        p.UserCreated.UserId,
+			UserLogin: p.UserCreated.UserLogin,
+			Email:     p.UserCreated.Email,
+			Fullname:  p.UserCreated.Fullname,
+		}
+	case *user_events.Event_UserUpdated:
+		change.Type = userChangeUpdated
+		change.UserId = p.UserUpdated.UserId
+		change.User = &user_service.GetUser{
+			Id:        p.UserUpdated.UserId,
+			UserLogin: p.UserUpdated.UserLogin,
+			Email:     p.UserUpdated.Email,
+			Fullname:  p.UserUpdated.Fullname,
+		}
+	case *user_events.Event_UserDeleted:
+		change.Type = userChangeDeleted
+		change.UserId = p.UserDeleted.UserId
+	case *user_events.Event_UserRestored:
+		change.Type = userChangeRestored
+		change.UserId = p.UserRestored.UserId
+	case *user_events.Event_UserPurged:
+		change.Type = userChangePurged
+		change.UserId = p.UserPurged.UserId
+	default:
+		return nil
+	}
+
+	return change
+}
+
+func watchTypes(types []string) (map[string]bool, error) {
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	filter := make(map[string]bool, len(types))
+	for _, t := range types {
+		switch t {
+		case userChangeCreated, userChangeUpdated, userChangeDeleted, userChangeRestored, userChangePurged:
+			filter[t] = true
+		default:
+			return nil, fmt.Errorf("unknown change type %q", t)
+		}
+	}
+	return filter, nil
+}
+
+// WatchUsers streams user changes as they are committed. Every change carries
+// a resume token; passing the last one seen on reconnect continues right
+// after it, as long as it is still within EVENT_STREAM_MAX_LEN events.
+func (f *UserService) WatchUsers(req *user_service.WatchUsersRequest, stream user_service.UserService_WatchUsersServer) error {
+	ctx := stream.Context()
+
+	logger.FromContext(ctx, f.log).Info("---WatchUsers--->>>", logger.Any("req", req))
+
+	filter, err := watchTypes(req.Types)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---WatchUsers--->>>", logger.Error(err))
+		return err
+	}
+
+	events := f.redis.Events()
+
+	after := req.ResumeToken
+	if after == "" {
+		after, err = events.LastID(ctx)
+		if err != nil {
+			logger.FromContext(ctx, f.log).Error("---WatchUsers--->>>", logger.Error(err))
+			return err
+		}
+	} else if _, _, err = storage.ParseStreamID(after); err != nil {
+		logger.FromContext(ctx, f.log).Error("---WatchUsers--->>>", logger.Error(err))
+		return errors.New("invalid resume token")
+	}
+
+	for {
+		entries, err := events.Read(ctx, after, watchBatchSize, watchBlock)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			logger.FromContext(ctx, f.log).Error("---WatchUsers--->>>", logger.Error(err))
+			return err
+		}
+
+		for _, entry := range entries {
+			after = entry.Id
+
+			change := userChangeFromEvent(entry.Event)
+			if change == nil || (filter != nil && !filter[change.Type]) {
+				continue
+			}
+			change.ResumeToken = entry.Id
+
+			if err := stream.Send(change); err != nil {
+				return err
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go_user_service/genproto/admin_service"
+	"go_user_service/outbox"
+	"go_user_service/pkg/check"
+	"go_user_service/pkg/events"
+	"go_user_service/pkg/logger"
+	"go_user_service/pkg/otp"
+	"go_user_service/pkg/webhook"
+	"go_user_service/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	webhookSecretLength = 40
+	webhookTestEvent    = "webhook_test"
+)
+
+func webhookToProto(hook *storage.Webhook, withSecret bool) *admin_service.Webhook {
+	out := &admin_service.Webhook{
+		Id:        hook.Id,
+		Url:       hook.Url,
+		Events:    hook.Events,
+		Active:    hook.Active,
+		CreatedAt: hook.CreatedAt.Format(time.RFC3339),
+	}
+	if withSecret {
+		out.Secret = hook.Secret
+	}
+	if !hook.UpdatedAt.IsZero() {
+		out.UpdatedAt = hook.UpdatedAt.Format(time.RFC3339)
+	}
+	return out
+}
+
+func webhookDeliveryToProto(d *storage.WebhookDelivery) *admin_service.WebhookDelivery {
+	return &admin_service.WebhookDelivery{
+		Id:           d.Id,
+		WebhookId:    d.WebhookId,
+		EventId:      d.EventId,
+		EventType:    d.EventType,
+		Attempt:      int64(d.Attempt),
+		ResponseCode: int64(d.ResponseCode),
+		Error:        d.Error,
+		DurationMs:   d.DurationMs,
+		CreatedAt:    d.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// validateWebhookEvents rejects event names that are never published.
+func validateWebhookEvents(names []string) error {
+	known := make(map[string]bool)
+	for _, name := range events.Names() {
+		known[name] = true
+	}
+	for _, name := range names {
+		if !known[name] {
+			return fmt.Errorf("unknown event %q", name)
+		}
+	}
+	return nil
+}
+
+func (f *AdminService) CreateWebhook(ctx context.Context, req *admin_service.CreateWebhookRequest) (*admin_service.Webhook, error) {
+	logger.FromContext(ctx, f.log).Info("---CreateWebhook--->>>", logger.String("url", req.Url), logger.Any("events", req.Events))
+
+	if err := webhook.ValidateURL(req.Url); err != nil {
+		return &admin_service.Webhook{}, err
+	}
+	if err := validateWebhookEvents(req.Events); err != nil {
+		return &admin_service.Webhook{}, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = otp.Token(webhookSecretLength); err != nil {
+			logger.FromContext(ctx, f.log).Error("---CreateWebhook--->>>", logger.Error(err))
+			return &admin_service.Webhook{}, err
+		}
+	}
+
+	hook := &storage.Webhook{
+		Url:    req.Url,
+		Secret: secret,
+		Events: req.Events,
+		Active: true,
+	}
+	if err := f.strg.Webhook().Create(ctx, hook); err != nil {
+		logger.FromContext(ctx, f.log).Error("---CreateWebhook--->>>", logger.Error(err))
+		return &admin_service.Webhook{}, err
+	}
+
+	return webhookToProto(hook, true), nil
+}
+
+func (f *AdminService) UpdateWebhook(ctx context.Context, req *admin_service.UpdateWebhookRequest) (*admin_service.Webhook, error) {
+	logger.FromContext(ctx, f.log).Info("---UpdateWebhook--->>>", logger.String("id", req.Id), logger.String("url", req.Url), logger.Any("events", req.Events))
+
+	if !check.IsValidUUID(req.Id) {
+		return &admin_service.Webhook{}, fmt.Errorf("invalid webhook id %q", req.Id)
+	}
+	if err := webhook.ValidateURL(req.Url); err != nil {
+		return &admin_service.Webhook{}, err
+	}
+	if err := validateWebhookEvents(req.Events); err != nil {
+		return &admin_service.Webhook{}, err
+	}
+
+	hook, err := f.strg.Webhook().GetById(ctx, req.Id)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---UpdateWebhook--->>>", logger.Error(err))
+		return &admin_service.Webhook{}, err
+	}
+
+	newSecret := req.Secret != "" || req.RotateSecret
+	if req.Secret != "" {
+		hook.Secret = req.Secret
+	} else if req.RotateSecret {
+		if hook.Secret, err = otp.Token(webhookSecretLength); err != nil {
+			logger.FromContext(ctx, f.log).Error("---UpdateWebhook--->>>", logger.Error(err))
+			return &admin_service.Webhook{}, err
+		}
+	}
+	hook.Url = req.Url
+	hook.Events = req.Events
+	hook.Active = req.Active
+
+	if err = f.strg.Webhook().Update(ctx, hook); err != nil {
+		logger.FromContext(ctx, f.log).Error("---UpdateWebhook--->>>", logger.Error(err))
+		return &admin_service.Webhook{}, err
+	}
+
+	return webhookToProto(hook, newSecret), nil
+}
+
+func (f *AdminService) DeleteWebhook(ctx context.Context, req *admin_service.WebhookPrimaryKey) (*emptypb.Empty, error) {
+	logger.FromContext(ctx, f.log).Info("---DeleteWebhook--->>>", logger.Any("req", req))
+
+	if !check.IsValidUUID(req.Id) {
+		return &emptypb.Empty{}, fmt.Errorf("invalid webhook id %q", req.Id)
+	}
+
+	if err := f.strg.Webhook().Delete(ctx, req.Id); err != nil {
+		logger.FromContext(ctx, f.log).Error("---DeleteWebhook--->>>", logger.Error(err))
+		return &emptypb.Empty{}, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (f *AdminService) ListWebhooks(ctx context.Context, req *admin_service.ListWebhooksRequest) (*admin_service.ListWebhooksResponse, error) {
+	logger.FromContext(ctx, f.log).Info("---ListWebhooks--->>>")
+
+	hooks, err := f.strg.Webhook().GetAll(ctx)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---ListWebhooks--->>>", logger.Error(err))
+		return &admin_service.ListWebhooksResponse{}, err
+	}
+
+	resp := &admin_service.ListWebhooksResponse{}
+	for _, hook := range hooks {
+		resp.Webhooks = append(resp.Webhooks, webhookToProto(hook, false))
+	}
+
+	return resp, nil
+}
+
+func (f *AdminService) ListWebhookDeliveries(ctx context.Context, req *admin_service.ListWebhookDeliveriesRequest) (*admin_service.ListWebhookDeliveriesResponse, error) {
+	logger.FromContext(ctx, f.log).Info("---ListWebhookDeliveries--->>>", logger.Any("req", req))
+
+	if !check.IsValidUUID(req.WebhookId) {
+		return &admin_service.ListWebhookDeliveriesResponse{}, fmt.Errorf("invalid webhook id %q", req.WebhookId)
+	}
+
+	deliveries, count, err := f.strg.Webhook().GetDeliveries(ctx, req.WebhookId, req.Offset, req.Limit)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---ListWebhookDeliveries--->>>", logger.Error(err))
+		return &admin_service.ListWebhookDeliveriesResponse{}, err
+	}
+
+	resp := &admin_service.ListWebhookDeliveriesResponse{Count: count}
+	for _, d := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, webhookDeliveryToProto(d))
+	}
+
+	return resp, nil
+}
+
+// SendTestWebhook posts a webhook_test event to the endpoint right away, even
+// when the webhook is disabled, and returns the logged attempt.
+func (f *AdminService) SendTestWebhook(ctx context.Context, req *admin_service.WebhookPrimaryKey) (*admin_service.WebhookDelivery, error) {
+	logger.FromContext(ctx, f.log).Info("---SendTestWebhook--->>>", logger.Any("req", req))
+
+	if !check.IsValidUUID(req.Id) {
+		return &admin_service.WebhookDelivery{}, fmt.Errorf("invalid webhook id %q", req.Id)
+	}
+
+	hook, err := f.strg.Webhook().GetById(ctx, req.Id)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---SendTestWebhook--->>>", logger.Error(err))
+		return &admin_service.WebhookDelivery{}, err
+	}
+
+	data, err := json.Marshal(map[string]string{"webhook_id": hook.Id})
+	if err != nil {
+		return &admin_service.WebhookDelivery{}, err
+	}
+	eventID := uuid.NewString()
+	body, err := webhook.Body(eventID, webhookTestEvent, time.Now().UTC().Format(time.RFC3339Nano), data)
+	if err != nil {
+		return &admin_service.WebhookDelivery{}, err
+	}
+
+	res := webhook.NewSender(f.cfg.WebhookTimeout).Send(ctx, hook.Url, hook.Secret, eventID, webhookTestEvent, body)
+	delivery, err := outbox.LogWebhookDelivery(ctx, f.strg, hook.Id, eventID, webhookTestEvent, 1, res)
+	if err != nil {
+		logger.FromContext(ctx, f.log).Error("---SendTestWebhook--->>>", logger.Error(err))
+		return &admin_service.WebhookDelivery{}, err
+	}
+
+	return webhookDeliveryToProto(delivery), nil
+}
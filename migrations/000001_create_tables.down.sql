@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS admins;
+DROP TABLE IF EXISTS users;
+DROP SEQUENCE IF EXISTS admin_external_id_seq;
+DROP SEQUENCE IF EXISTS user_external_id_seq;
+DROP TYPE IF EXISTS gender;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS tasks;
+DROP SEQUENCE IF EXISTS task_external_id_seq;
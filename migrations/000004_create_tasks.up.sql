@@ -0,0 +1,21 @@
+CREATE SEQUENCE IF NOT EXISTS task_external_id_seq START WITH 1;
+
+-- user_id has no foreign key: user deletes and purges reach the tasks
+-- through the outbox, after the user row has changed
+CREATE TABLE IF NOT EXISTS tasks (
+    id UUID PRIMARY KEY,
+    external_id VARCHAR(35) UNIQUE NOT NULL,
+    user_id UUID NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    task_status VARCHAR(32) NOT NULL,
+    task_description TEXT,
+    deadline TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    -- set by DeleteByOwner, so RestoreByOwner leaves tasks the user deleted
+    -- themselves alone
+    deleted_by_owner BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS tasks_user_idx ON tasks (user_id);
@@ -0,0 +1,84 @@
+// Package migrations embeds the SQL schema migrations into the binary and
+// applies them with golang-migrate.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"go_user_service/config"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// New returns a migrator for the database in cfg using the embedded files.
+// The caller must Close it.
+func New(cfg config.Config) (*migrate.Migrate, error) {
+	src, err := iofs.New(FS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithSourceInstance("iofs", src, fmt.Sprintf(
+		"pgx://%s:%s@%s:%d/%s?sslmode=disable",
+		cfg.PostgresUser,
+		cfg.PostgresPassword,
+		cfg.PostgresHost,
+		cfg.PostgresPort,
+		cfg.PostgresDatabase,
+	))
+}
+
+// Latest returns the highest migration version embedded in the binary.
+func Latest() (uint, error) {
+	files, err := fs.Glob(FS, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint64
+	for _, name := range files {
+		version, err := strconv.ParseUint(strings.SplitN(name, "_", 2)[0], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("bad migration file name %s: %w", name, err)
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+
+	return uint(latest), nil
+}
+
+// Check returns an error if the database schema is dirty or older than the
+// migrations embedded in the binary.
+func Check(m *migrate.Migrate) error {
+	latest, err := Latest()
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("database has no schema, expected version %d", latest)
+	}
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("database schema version %d is dirty, fix it and run migrate force", version)
+	}
+	if version < latest {
+		return fmt.Errorf("database schema version %d is behind expected version %d, run migrate up", version, latest)
+	}
+
+	return nil
+}
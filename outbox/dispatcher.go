@@ -0,0 +1,195 @@
+// Package outbox delivers the messages that services write to the outbox
+// table. Writing the message in the same transaction as the change it
+// belongs to, and sending it from here, keeps slow or failing deliveries
+// out of the RPC path and makes sure nothing is lost once the change is
+// committed.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_user_service/config"
+	"go_user_service/pkg/logger"
+	"go_user_service/pkg/tracing"
+	"go_user_service/storage"
+	"math/rand"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Handler delivers a single message. A returned error schedules a retry,
+// unless it wraps ErrExpired or ErrPermanent.
+type Handler func(ctx context.Context, msg *storage.OutboxMessage) error
+
+// ErrExpired is returned by handlers for messages that are no longer worth
+// delivering. They are moved to the dead letters without further attempts.
+var ErrExpired = errors.New("outbox message expired")
+
+// ErrPermanent is returned by handlers for failures a retry can't fix, such
+// as a receiver that doesn't support the call. The message is moved to the
+// dead letters without further attempts.
+var ErrPermanent = errors.New("outbox message can't be delivered")
+
+// purgeInterval is how often sent messages older than Retention are deleted.
+const purgeInterval = time.Hour
+
+type Options struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// MaxAttempts is how many deliveries are tried before the message is
+	// moved to the dead letters
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Lease hides a claimed message from other replicas while it is being
+	// delivered, it has to be longer than any handler takes
+	Lease time.Duration
+	// Retention is how long sent messages are kept, 0 keeps them forever
+	Retention time.Duration
+}
+
+func OptionsFromConfig(cfg config.Config) Options {
+	return Options{
+		PollInterval: cfg.OutboxPollInterval,
+		BatchSize:    cfg.OutboxBatchSize,
+		MaxAttempts:  cfg.OutboxMaxAttempts,
+		BackoffBase:  cfg.OutboxBackoffBase,
+		BackoffMax:   cfg.OutboxBackoffMax,
+		Lease:        cfg.OutboxLease,
+		Retention:    cfg.OutboxRetention,
+	}
+}
+
+type Dispatcher struct {
+	strg     storage.StorageI
+	log      logger.LoggerI
+	opts     Options
+	handlers map[string]Handler
+}
+
+func NewDispatcher(strg storage.StorageI, log logger.LoggerI, opts Options) *Dispatcher {
+	return &Dispatcher{
+		strg:     strg,
+		log:      log,
+		opts:     opts,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Handle registers the handler for messages of kind.
+func (d *Dispatcher) Handle(kind string, h Handler) {
+	d.handlers[kind] = h
+}
+
+// Run delivers messages until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	var purged time.Time
+	for {
+		if d.opts.Retention > 0 && time.Since(purged) >= purgeInterval {
+			purged = time.Now()
+			if err := d.purge(ctx); err != nil && ctx.Err() == nil {
+				d.log.Error("---OutboxPurge--->>>", logger.Error(err))
+			}
+		}
+
+		// keep going without waiting while full batches come back
+		n, err := d.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.log.Error("---OutboxDispatch--->>>", logger.Error(err))
+		}
+		if err == nil && n == d.opts.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge deletes the messages that were sent more than Retention ago.
+func (d *Dispatcher) purge(ctx context.Context) error {
+	n, err := d.strg.Outbox().PurgeSent(ctx, time.Now().Add(-d.opts.Retention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		d.log.Info("purged sent outbox messages", logger.Int64("count", n))
+	}
+	return nil
+}
+
+// RunOnce claims one batch of due messages and delivers it, returning the
+// number of messages claimed.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	msgs, err := d.strg.Outbox().Claim(ctx, d.opts.BatchSize, d.opts.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, msg := range msgs {
+		d.deliver(ctx, msg)
+	}
+	return len(msgs), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, msg *storage.OutboxMessage) {
+	ctx, span := tracing.Start(ctx, "outbox.deliver "+msg.Kind, trace.WithAttributes(
+		attribute.String("outbox.id", msg.Id),
+		attribute.Int("outbox.attempt", msg.Attempts),
+	))
+
+	var err error
+	if h, ok := d.handlers[msg.Kind]; ok {
+		err = h(ctx, msg)
+	} else {
+		err = fmt.Errorf("no handler for outbox message kind %q", msg.Kind)
+	}
+	tracing.End(span, err)
+
+	if err == nil {
+		if err = d.strg.Outbox().MarkSent(ctx, msg.Id); err != nil {
+			d.log.Error("error while marking outbox message sent", logger.String("id", msg.Id), logger.Error(err))
+		}
+		return
+	}
+
+	fields := append([]logger.Field{
+		logger.String("id", msg.Id), logger.String("kind", msg.Kind), logger.Int("attempts", msg.Attempts), logger.Error(err),
+	}, tracing.LogFields(ctx)...)
+
+	dead := msg.Attempts >= d.opts.MaxAttempts || errors.Is(err, ErrExpired) || errors.Is(err, ErrPermanent)
+	if dead {
+		d.log.Error("outbox message moved to dead letters", fields...)
+	} else {
+		d.log.Warn("outbox delivery failed", fields...)
+	}
+
+	if err := d.strg.Outbox().MarkFailed(ctx, msg.Id, err.Error(), d.backoff(msg.Attempts), dead); err != nil {
+		d.log.Error("error while marking outbox message failed", logger.String("id", msg.Id), logger.Error(err))
+	}
+}
+
+// backoff doubles the delay with every attempt up to BackoffMax and adds up
+// to 20% jitter so failed messages don't all come back at once.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.opts.BackoffBase
+	for i := 1; i < attempts && delay < d.opts.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > d.opts.BackoffMax {
+		delay = d.opts.BackoffMax
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
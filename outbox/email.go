@@ -0,0 +1,67 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go_user_service/pkg/mailer"
+	"go_user_service/pkg/metrics"
+	"go_user_service/pkg/tracing"
+	"go_user_service/storage"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const KindEmail = "email"
+
+// Email is the payload of an email message. It is rendered when it is
+// delivered, in the first of Langs that has templates. An email that is
+// still undelivered at ExpiresAt goes to the dead letters instead, since
+// its code no longer works.
+type Email struct {
+	Kind      mailer.Kind `json:"kind"`
+	To        string      `json:"to"`
+	Langs     []string    `json:"langs,omitempty"`
+	Code      string      `json:"code"`
+	Minutes   int         `json:"minutes"`
+	ExpiresAt time.Time   `json:"expires_at,omitempty"`
+}
+
+func NewEmail(email Email) (*storage.OutboxMessage, error) {
+	payload, err := json.Marshal(email)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.OutboxMessage{Kind: KindEmail, Payload: payload}, nil
+}
+
+// EmailHandler renders email messages and sends them with m.
+func EmailHandler(m mailer.Mailer, serviceName string) Handler {
+	return func(ctx context.Context, msg *storage.OutboxMessage) error {
+		var email Email
+		if err := json.Unmarshal(msg.Payload, &email); err != nil {
+			return err
+		}
+		if !email.ExpiresAt.IsZero() && time.Now().After(email.ExpiresAt) {
+			return fmt.Errorf("%w at %s", ErrExpired, email.ExpiresAt.Format(time.RFC3339))
+		}
+
+		rendered, err := mailer.Render(email.Kind, email.Langs, email.To, mailer.Data{
+			Code:    email.Code,
+			Service: serviceName,
+			Minutes: email.Minutes,
+		})
+		if err != nil {
+			return err
+		}
+
+		sendCtx, span := tracing.Start(ctx, "mail.send", trace.WithAttributes(attribute.String("mail.kind", string(email.Kind))))
+		err = m.Send(sendCtx, rendered)
+		tracing.End(span, err)
+
+		metrics.Email(string(email.Kind), err)
+		return err
+	}
+}
@@ -0,0 +1,63 @@
+package outbox
+
+import (
+	"context"
+	"go_user_service/config"
+	"go_user_service/pkg/logger"
+	"go_user_service/pkg/mailer"
+	"go_user_service/storage"
+	"go_user_service/storage/memory"
+	"testing"
+	"time"
+)
+
+type countingMailer struct {
+	sent int
+}
+
+func (m *countingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.sent++
+	return nil
+}
+
+func TestExpiredEmailIsDeadLettered(t *testing.T) {
+	ctx := context.Background()
+	strg := memory.New(memory.NewRedis(config.Load()))
+	m := &countingMailer{}
+
+	d := NewDispatcher(strg, logger.Nop(), Options{BatchSize: 10, MaxAttempts: 8, BackoffBase: time.Second, BackoffMax: time.Minute, Lease: time.Minute})
+	d.Handle(KindEmail, EmailHandler(m, "test"))
+
+	for _, expiresAt := range []time.Time{time.Now().Add(-time.Second), time.Now().Add(time.Minute)} {
+		msg, err := NewEmail(Email{Kind: mailer.RegisterOTP, To: "someone@gmail.com", Code: "123456", Minutes: 2, ExpiresAt: expiresAt})
+		if err != nil {
+			t.Fatalf("NewEmail: %v", err)
+		}
+		if err = strg.Outbox().Add(ctx, msg); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	if _, err := d.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if m.sent != 1 {
+		t.Fatalf("sent %d emails, want only the one that has not expired", m.sent)
+	}
+
+	dead, _, err := strg.Outbox().List(ctx, &storage.OutboxListRequest{Offset: 1, Limit: 10, Status: storage.OutboxDead})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(dead) != 1 || dead[0].Attempts != 1 {
+		t.Fatalf("dead letters are %+v, want the expired email after one attempt", dead)
+	}
+
+	sent, _, err := strg.Outbox().List(ctx, &storage.OutboxListRequest{Offset: 1, Limit: 10, Status: storage.OutboxSent})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(sent) != 1 || string(sent[0].Payload) != "{}" {
+		t.Fatalf("sent messages are %+v, want one with the payload cleared", sent)
+	}
+}
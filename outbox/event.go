@@ -0,0 +1,33 @@
+package outbox
+
+import (
+	"context"
+	"go_user_service/genproto/user_events"
+	"go_user_service/pkg/events"
+	"go_user_service/storage"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const KindEvent = "event"
+
+// NewEvent wraps ev in an outbox message. The payload is protojson so it
+// stays readable in the outbox table.
+func NewEvent(ev *user_events.Event) (*storage.OutboxMessage, error) {
+	payload, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.OutboxMessage{Kind: KindEvent, Payload: payload}, nil
+}
+
+// EventHandler publishes event messages with pub.
+func EventHandler(pub events.Publisher) Handler {
+	return func(ctx context.Context, msg *storage.OutboxMessage) error {
+		var ev user_events.Event
+		if err := protojson.Unmarshal(msg.Payload, &ev); err != nil {
+			return err
+		}
+		return pub.Publish(ctx, &ev)
+	}
+}
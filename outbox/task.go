@@ -0,0 +1,101 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go_user_service/genproto/task_service"
+	"go_user_service/genproto/user_events"
+	"go_user_service/genproto/user_service"
+	"go_user_service/storage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const KindTaskCascade = "task_cascade"
+
+const (
+	TaskCascadeDelete  = "delete"
+	TaskCascadeRestore = "restore"
+	TaskCascadePurge   = "purge"
+)
+
+// TaskCascade is the payload of a task_cascade message: apply Action to
+// every task of UserId.
+type TaskCascade struct {
+	UserId string `json:"user_id"`
+	Action string `json:"action"`
+}
+
+func NewTaskCascade(userID, action string) (*storage.OutboxMessage, error) {
+	payload, err := json.Marshal(TaskCascade{UserId: userID, Action: action})
+	if err != nil {
+		return nil, err
+	}
+	return &storage.OutboxMessage{Kind: KindTaskCascade, Payload: payload}, nil
+}
+
+// NewTaskCascadeFor returns the task_cascade message that follows ev, or nil
+// when ev doesn't concern the tasks of a user.
+func NewTaskCascadeFor(ev *user_events.Event) (*storage.OutboxMessage, error) {
+	switch p := ev.Payload.(type) {
+	case *user_events.Event_UserDeleted:
+		return NewTaskCascade(p.UserDeleted.UserId, TaskCascadeDelete)
+	case *user_events.Event_UserRestored:
+		return NewTaskCascade(p.UserRestored.UserId, TaskCascadeRestore)
+	case *user_events.Event_UserPurged:
+		return NewTaskCascade(p.UserPurged.UserId, TaskCascadePurge)
+	}
+	return nil, nil
+}
+
+// TaskCascadeHandler applies task_cascade messages through the task service.
+// Messages can be delivered out of order when one of them is retried, so a
+// delete or restore is only applied while the user is still in that state;
+// a delete that failed and is retried after the user was restored does
+// nothing. A task service without the TaskOwner RPCs answers Unimplemented,
+// and such messages go straight to the dead letters.
+func TaskCascadeHandler(strg storage.StorageI, tasks task_service.TaskServiceClient) Handler {
+	return func(ctx context.Context, msg *storage.OutboxMessage) error {
+		var cascade TaskCascade
+		if err := json.Unmarshal(msg.Payload, &cascade); err != nil {
+			return err
+		}
+
+		err := applyTaskCascade(ctx, strg, tasks, cascade)
+		if status.Code(err) == codes.Unimplemented {
+			return fmt.Errorf("%w: the task service doesn't support the %s cascade, deploy one with the TaskOwner RPCs or set TASK_CASCADE=false: %v",
+				ErrPermanent, cascade.Action, err)
+		}
+		return err
+	}
+}
+
+func applyTaskCascade(ctx context.Context, strg storage.StorageI, tasks task_service.TaskServiceClient, cascade TaskCascade) error {
+	owner := &task_service.TaskOwner{OwnerId: cascade.UserId}
+
+	if cascade.Action == TaskCascadePurge {
+		_, err := tasks.PurgeByOwner(ctx, owner)
+		return err
+	}
+
+	active, err := strg.User().Check(ctx, &user_service.UserPrimaryKey{Id: cascade.UserId})
+	if err != nil {
+		return err
+	}
+
+	switch cascade.Action {
+	case TaskCascadeDelete:
+		if !active.Check {
+			_, err = tasks.DeleteByOwner(ctx, owner)
+		}
+	case TaskCascadeRestore:
+		if active.Check {
+			_, err = tasks.RestoreByOwner(ctx, owner)
+		}
+	default:
+		err = fmt.Errorf("unknown task cascade action %q", cascade.Action)
+	}
+	return err
+}
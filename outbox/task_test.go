@@ -0,0 +1,127 @@
+package outbox
+
+import (
+	"context"
+	"go_user_service/config"
+	"go_user_service/genproto/task_service"
+	"go_user_service/genproto/user_service"
+	"go_user_service/pkg/logger"
+	"go_user_service/storage"
+	"go_user_service/storage/memory"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ownerTasks is a task service that records the TaskOwner calls it gets
+// and answers them with err.
+type ownerTasks struct {
+	task_service.TaskServiceClient
+	calls []string
+	err   error
+}
+
+func (o *ownerTasks) call(action string) (*task_service.TaskOwnerResp, error) {
+	o.calls = append(o.calls, action)
+	return &task_service.TaskOwnerResp{}, o.err
+}
+
+func (o *ownerTasks) DeleteByOwner(ctx context.Context, req *task_service.TaskOwner, opts ...grpc.CallOption) (*task_service.TaskOwnerResp, error) {
+	return o.call(TaskCascadeDelete)
+}
+
+func (o *ownerTasks) RestoreByOwner(ctx context.Context, req *task_service.TaskOwner, opts ...grpc.CallOption) (*task_service.TaskOwnerResp, error) {
+	return o.call(TaskCascadeRestore)
+}
+
+func (o *ownerTasks) PurgeByOwner(ctx context.Context, req *task_service.TaskOwner, opts ...grpc.CallOption) (*task_service.TaskOwnerResp, error) {
+	return o.call(TaskCascadePurge)
+}
+
+func newTaskOwner(t *testing.T, strg storage.StorageI) string {
+	t.Helper()
+	user, err := strg.User().Create(context.Background(), &user_service.CreateUser{
+		Gender:       "female",
+		Fullname:     "Task Owner",
+		Email:        "task.owner@gmail.com",
+		UserPassword: "secret",
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return user.Id
+}
+
+func runCascade(t *testing.T, strg storage.StorageI, tasks *ownerTasks, userID, action string) error {
+	t.Helper()
+	msg, err := NewTaskCascade(userID, action)
+	if err != nil {
+		t.Fatalf("NewTaskCascade: %v", err)
+	}
+	return TaskCascadeHandler(strg, tasks)(context.Background(), msg)
+}
+
+func TestTaskCascadeSkipsStaleActions(t *testing.T) {
+	ctx := context.Background()
+	strg := memory.New(memory.NewRedis(config.Load()))
+	userID := newTaskOwner(t, strg)
+	key := &user_service.UserPrimaryKey{Id: userID}
+	tasks := &ownerTasks{}
+
+	// the user was deleted and restored, the delete arrives last
+	if err := runCascade(t, strg, tasks, userID, TaskCascadeDelete); err != nil {
+		t.Fatalf("delete of an active user: %v", err)
+	}
+	if len(tasks.calls) != 0 {
+		t.Fatalf("delete of an active user called %v, want nothing", tasks.calls)
+	}
+
+	if _, err := strg.User().Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	// and the other way around
+	if err := runCascade(t, strg, tasks, userID, TaskCascadeRestore); err != nil {
+		t.Fatalf("restore of a deleted user: %v", err)
+	}
+	if len(tasks.calls) != 0 {
+		t.Fatalf("restore of a deleted user called %v, want nothing", tasks.calls)
+	}
+
+	if err := runCascade(t, strg, tasks, userID, TaskCascadeDelete); err != nil {
+		t.Fatalf("delete of a deleted user: %v", err)
+	}
+	if len(tasks.calls) != 1 || tasks.calls[0] != TaskCascadeDelete {
+		t.Fatalf("delete of a deleted user called %v, want DeleteByOwner", tasks.calls)
+	}
+}
+
+func TestTaskCascadeUnimplementedIsDeadLettered(t *testing.T) {
+	ctx := context.Background()
+	strg := memory.New(memory.NewRedis(config.Load()))
+	tasks := &ownerTasks{err: status.Error(codes.Unimplemented, "unknown method PurgeByOwner")}
+
+	d := NewDispatcher(strg, logger.Nop(), Options{BatchSize: 10, MaxAttempts: 8, Lease: time.Minute})
+	d.Handle(KindTaskCascade, TaskCascadeHandler(strg, tasks))
+
+	msg, err := NewTaskCascade(newTaskOwner(t, strg), TaskCascadePurge)
+	if err != nil {
+		t.Fatalf("NewTaskCascade: %v", err)
+	}
+	if err = strg.Outbox().Add(ctx, msg); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err = d.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	dead, _, err := strg.Outbox().List(ctx, &storage.OutboxListRequest{Offset: 1, Limit: 10, Status: storage.OutboxDead})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(dead) != 1 || dead[0].Attempts != 1 {
+		t.Fatalf("dead letters are %+v, want the cascade after one attempt", dead)
+	}
+}
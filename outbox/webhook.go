@@ -0,0 +1,122 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go_user_service/genproto/user_events"
+	"go_user_service/pkg/events"
+	"go_user_service/pkg/webhook"
+	"go_user_service/storage"
+
+	"github.com/jackc/pgx/v4"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const KindWebhook = "webhook"
+
+// WebhookDelivery is the payload of a webhook message: one event for one
+// endpoint. Body is posted as is, so every retry sends the same document.
+type WebhookDelivery struct {
+	WebhookId string          `json:"webhook_id"`
+	EventId   string          `json:"event_id"`
+	EventType string          `json:"event_type"`
+	Body      json.RawMessage `json:"body"`
+}
+
+// Subscribed reports whether hook wants events named name.
+func Subscribed(hook *storage.Webhook, name string) bool {
+	if !hook.Active {
+		return false
+	}
+	if len(hook.Events) == 0 {
+		return true
+	}
+	for _, e := range hook.Events {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// NewWebhookDeliveries returns a webhook message for every hook subscribed
+// to ev.
+func NewWebhookDeliveries(hooks []*storage.Webhook, ev *user_events.Event) ([]*storage.OutboxMessage, error) {
+	name := events.Name(ev)
+
+	var (
+		msgs []*storage.OutboxMessage
+		body []byte
+	)
+	for _, hook := range hooks {
+		if !Subscribed(hook, name) {
+			continue
+		}
+		if body == nil {
+			data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(events.Payload(ev))
+			if err != nil {
+				return nil, err
+			}
+			if body, err = webhook.Body(ev.Id, name, ev.OccurredAt, data); err != nil {
+				return nil, err
+			}
+		}
+
+		payload, err := json.Marshal(WebhookDelivery{
+			WebhookId: hook.Id,
+			EventId:   ev.Id,
+			EventType: name,
+			Body:      body,
+		})
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, &storage.OutboxMessage{Kind: KindWebhook, Payload: payload})
+	}
+	return msgs, nil
+}
+
+// WebhookHandler posts webhook messages and logs every attempt. Messages
+// for deleted or disabled webhooks are dropped.
+func WebhookHandler(strg storage.StorageI, sender *webhook.Sender) Handler {
+	return func(ctx context.Context, msg *storage.OutboxMessage) error {
+		var delivery WebhookDelivery
+		if err := json.Unmarshal(msg.Payload, &delivery); err != nil {
+			return err
+		}
+
+		hook, err := strg.Webhook().GetById(ctx, delivery.WebhookId)
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && !hook.Active) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		res := sender.Send(ctx, hook.Url, hook.Secret, delivery.EventId, delivery.EventType, delivery.Body)
+		if _, err = LogWebhookDelivery(ctx, strg, hook.Id, delivery.EventId, delivery.EventType, msg.Attempts, res); err != nil {
+			return err
+		}
+		return res.Err
+	}
+}
+
+// LogWebhookDelivery stores the outcome of an attempt in the delivery log.
+func LogWebhookDelivery(ctx context.Context, strg storage.StorageI, hookID, eventID, eventType string, attempt int, res webhook.Result) (*storage.WebhookDelivery, error) {
+	entry := &storage.WebhookDelivery{
+		WebhookId:    hookID,
+		EventId:      eventID,
+		EventType:    eventType,
+		Attempt:      attempt,
+		ResponseCode: res.StatusCode,
+		DurationMs:   res.Duration.Milliseconds(),
+	}
+	if res.Err != nil {
+		entry.Error = res.Err.Error()
+	}
+	if err := strg.Webhook().AddDelivery(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
@@ -0,0 +1,133 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"go_user_service/config"
+	"go_user_service/pkg/logger"
+	"go_user_service/pkg/webhook"
+	"go_user_service/storage"
+	"go_user_service/storage/memory"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newWebhookDispatcher(strg storage.StorageI) *Dispatcher {
+	d := NewDispatcher(strg, logger.Nop(), Options{BatchSize: 10, MaxAttempts: 8, Lease: time.Minute})
+	d.Handle(KindWebhook, WebhookHandler(strg, webhook.NewSender(time.Second)))
+	return d
+}
+
+func addWebhookMessage(t *testing.T, strg storage.StorageI, hookID string) *storage.OutboxMessage {
+	payload, err := json.Marshal(WebhookDelivery{
+		WebhookId: hookID,
+		EventId:   "event-1",
+		EventType: "user_created",
+		Body:      json.RawMessage(`{"id":"event-1"}`),
+	})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	msg := &storage.OutboxMessage{Kind: KindWebhook, Payload: payload}
+	if err = strg.Outbox().Add(context.Background(), msg); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	return msg
+}
+
+func TestWebhookRetriesAndLogsDeliveries(t *testing.T) {
+	ctx := context.Background()
+	strg := memory.New(memory.NewRedis(config.Load()))
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	hook := &storage.Webhook{Url: srv.URL, Secret: "secret", Active: true}
+	if err := strg.Webhook().Create(ctx, hook); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	msg := addWebhookMessage(t, strg, hook.Id)
+	d := newWebhookDispatcher(strg)
+
+	// the first attempt gets a 500 and is retried, the second succeeds
+	for i := 0; i < 2; i++ {
+		if _, err := d.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+	}
+
+	deliveries, count, err := strg.Webhook().GetDeliveries(ctx, hook.Id, 1, 10)
+	if err != nil {
+		t.Fatalf("GetDeliveries: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("logged %d deliveries, want 2", count)
+	}
+	if failed := deliveries[1]; failed.Attempt != 1 || failed.ResponseCode != http.StatusInternalServerError || failed.Error == "" {
+		t.Fatalf("first delivery is %+v, want attempt 1 with a 500 and an error", failed)
+	}
+	if ok := deliveries[0]; ok.Attempt != 2 || ok.ResponseCode != http.StatusOK || ok.Error != "" {
+		t.Fatalf("second delivery is %+v, want attempt 2 with a 200", ok)
+	}
+
+	sent, _, err := strg.Outbox().List(ctx, &storage.OutboxListRequest{Offset: 1, Limit: 10, Status: storage.OutboxSent})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(sent) != 1 || sent[0].Id != msg.Id || sent[0].Attempts != 2 {
+		t.Fatalf("sent messages are %+v, want the webhook after 2 attempts", sent)
+	}
+}
+
+func TestWebhookDropsDisabledAndDeletedHooks(t *testing.T) {
+	ctx := context.Background()
+	strg := memory.New(memory.NewRedis(config.Load()))
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer srv.Close()
+
+	disabled := &storage.Webhook{Url: srv.URL, Secret: "secret", Active: true}
+	deleted := &storage.Webhook{Url: srv.URL, Secret: "secret", Active: true}
+	for _, hook := range []*storage.Webhook{disabled, deleted} {
+		if err := strg.Webhook().Create(ctx, hook); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		addWebhookMessage(t, strg, hook.Id)
+	}
+	disabled.Active = false
+	if err := strg.Webhook().Update(ctx, disabled); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := strg.Webhook().Delete(ctx, deleted.Id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if _, err := newWebhookDispatcher(strg).RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Fatalf("endpoint got %d requests, want none", n)
+	}
+	sent, _, err := strg.Outbox().List(ctx, &storage.OutboxListRequest{Offset: 1, Limit: 10, Status: storage.OutboxSent})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("%d messages were dropped, want both", len(sent))
+	}
+	if _, count, err := strg.Webhook().GetDeliveries(ctx, disabled.Id, 1, 10); err != nil || count != 0 {
+		t.Fatalf("disabled webhook logged %d deliveries, %v, want none", count, err)
+	}
+}
@@ -16,12 +16,27 @@ func ValidatePhone(phone string) error {
 
 func ValidateMail(mail string) error {
 	mailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@(gmail\.com|mail\.ru)$`)
-	if mailRegex.MatchString(mail) {
+	if !mailRegex.MatchString(mail) {
 		return errors.New("mail is not valid")
 	}
 	return nil
 }
 
+func ValidateGender(gender string) error {
+	switch gender {
+	case "male", "female", "other":
+		return nil
+	}
+	return errors.New("gender is not valid")
+}
+
+func ValidateDate(date string) error {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return errors.New("wrong date format")
+	}
+	return nil
+}
+
 func ValidateBitrthday(birthday string, age int) error {
 	layout := "2006-01-02"
 
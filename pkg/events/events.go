@@ -0,0 +1,138 @@
+// Package events publishes user and admin lifecycle events to other
+// services. Events are defined in genproto/user_events and reach a
+// Publisher through the outbox, so they are only sent for committed changes.
+package events
+
+import (
+	"context"
+	"fmt"
+	"go_user_service/config"
+	"go_user_service/genproto/user_events"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+// Publisher sends an event to everyone interested in it.
+type Publisher interface {
+	Publish(ctx context.Context, ev *user_events.Event) error
+	Close() error
+}
+
+// New returns the publisher selected by cfg.EventsDriver, or nil when events
+// are turned off.
+func New(cfg config.Config) (Publisher, error) {
+	switch cfg.EventsDriver {
+	case "", "none":
+		return nil, nil
+	case "nats":
+		return NewNATS(cfg.NatsURL, cfg.EventsSubjectPrefix, cfg.NatsTimeout)
+	case "inprocess":
+		return NewInProcess(), nil
+	default:
+		return nil, fmt.Errorf("unknown events driver %q", cfg.EventsDriver)
+	}
+}
+
+// Name returns the name of the payload set on ev, e.g. user_created.
+func Name(ev *user_events.Event) string {
+	m := ev.ProtoReflect()
+	oneof := m.Descriptor().Oneofs().ByName("payload")
+	if fd := m.WhichOneof(oneof); fd != nil {
+		return string(fd.Name())
+	}
+	return ""
+}
+
+// Names lists every event name in the order of the Event payload fields.
+func Names() []string {
+	fields := (&user_events.Event{}).ProtoReflect().Descriptor().Oneofs().ByName("payload").Fields()
+	names := make([]string, fields.Len())
+	for i := range names {
+		names[i] = string(fields.Get(i).Name())
+	}
+	return names
+}
+
+// Payload returns the message set in ev's payload.
+func Payload(ev *user_events.Event) proto.Message {
+	m := ev.ProtoReflect()
+	fd := m.WhichOneof(m.Descriptor().Oneofs().ByName("payload"))
+	if fd == nil {
+		return nil
+	}
+	return m.Get(fd).Message().Interface()
+}
+
+// Subject is the broker subject ev is published on.
+func Subject(prefix string, ev *user_events.Event) string {
+	if prefix == "" {
+		return Name(ev)
+	}
+	return prefix + "." + Name(ev)
+}
+
+func newEvent() *user_events.Event {
+	return &user_events.Event{
+		Id:         uuid.NewString(),
+		OccurredAt: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+}
+
+func UserCreated(p *user_events.UserCreated) *user_events.Event {
+	ev := newEvent()
+	ev.Payload = &user_events.Event_UserCreated{UserCreated: p}
+	return ev
+}
+
+func UserUpdated(p *user_events.UserUpdated) *user_events.Event {
+	ev := newEvent()
+	ev.Payload = &user_events.Event_UserUpdated{UserUpdated: p}
+	return ev
+}
+
+func UserDeleted(userID string) *user_events.Event {
+	ev := newEvent()
+	ev.Payload = &user_events.Event_UserDeleted{UserDeleted: &user_events.UserDeleted{UserId: userID}}
+	return ev
+}
+
+func UserRestored(userID string) *user_events.Event {
+	ev := newEvent()
+	ev.Payload = &user_events.Event_UserRestored{UserRestored: &user_events.UserRestored{UserId: userID}}
+	return ev
+}
+
+func UserPurged(userID string) *user_events.Event {
+	ev := newEvent()
+	ev.Payload = &user_events.Event_UserPurged{UserPurged: &user_events.UserPurged{UserId: userID}}
+	return ev
+}
+
+func PasswordChanged(accountType, accountID string) *user_events.Event {
+	ev := newEvent()
+	ev.Payload = &user_events.Event_PasswordChanged{PasswordChanged: &user_events.PasswordChanged{
+		AccountType: accountType,
+		AccountId:   accountID,
+	}}
+	return ev
+}
+
+func AdminCreated(p *user_events.AdminCreated) *user_events.Event {
+	ev := newEvent()
+	ev.Payload = &user_events.Event_AdminCreated{AdminCreated: p}
+	return ev
+}
+
+func AdminUpdated(p *user_events.AdminUpdated) *user_events.Event {
+	ev := newEvent()
+	ev.Payload = &user_events.Event_AdminUpdated{AdminUpdated: p}
+	return ev
+}
+
+func AdminDeleted(adminID string) *user_events.Event {
+	ev := newEvent()
+	ev.Payload = &user_events.Event_AdminDeleted{AdminDeleted: &user_events.AdminDeleted{AdminId: adminID}}
+	return ev
+}
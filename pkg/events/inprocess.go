@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"go_user_service/genproto/user_events"
+	"sync"
+)
+
+// InProcess hands events to subscribers in the same process. It is meant
+// for local runs and tests.
+type InProcess struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]func(*user_events.Event)
+}
+
+func NewInProcess() *InProcess {
+	return &InProcess{subs: make(map[int]func(*user_events.Event))}
+}
+
+// Subscribe calls fn for every published event until the returned function
+// is called. fn runs on the publishing goroutine and must not block.
+func (p *InProcess) Subscribe(fn func(*user_events.Event)) (unsubscribe func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.nextID++
+	id := p.nextID
+	p.subs[id] = fn
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.subs, id)
+	}
+}
+
+func (p *InProcess) Publish(ctx context.Context, ev *user_events.Event) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, fn := range p.subs {
+		fn(ev)
+	}
+	return nil
+}
+
+func (p *InProcess) Close() error {
+	return nil
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"context"
+	"go_user_service/genproto/user_events"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/proto"
+)
+
+type natsPublisher struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+// NewNATS publishes events as binary protobuf on <prefix>.<event name>.
+// The event id is sent in the Nats-Msg-Id header, so JetStream streams
+// drop the duplicates a retried delivery can produce.
+func NewNATS(url, prefix string, timeout time.Duration) (Publisher, error) {
+	conn, err := nats.Connect(url,
+		nats.Name("go_user_service"),
+		nats.Timeout(timeout),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &natsPublisher{conn: conn, prefix: prefix}, nil
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, ev *user_events.Event) error {
+	data, err := proto.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(Subject(p.prefix, ev))
+	msg.Data = data
+	msg.Header.Set(nats.MsgIdHdr, ev.Id)
+	msg.Header.Set("Content-Type", "application/protobuf")
+
+	if err = p.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	// wait for the server to take it, so a failure is retried by the outbox
+	return p.conn.FlushWithContext(ctx)
+}
+
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
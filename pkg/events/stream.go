@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"go_user_service/genproto/user_events"
+	"go_user_service/storage"
+)
+
+type streamPublisher struct {
+	stream storage.EventStreamI
+}
+
+// NewStreamPublisher appends events to the redis event stream that
+// WatchUsers reads, so watchers on every replica see them.
+func NewStreamPublisher(stream storage.EventStreamI) Publisher {
+	return streamPublisher{stream: stream}
+}
+
+func (p streamPublisher) Publish(ctx context.Context, ev *user_events.Event) error {
+	_, err := p.stream.Append(ctx, ev)
+	return err
+}
+
+func (p streamPublisher) Close() error {
+	return nil
+}
+
+type multi []Publisher
+
+// Multi publishes every event to all pubs. When one fails the whole publish
+// is retried, so the others can see an event twice; consumers drop
+// duplicates by event id.
+func Multi(pubs ...Publisher) Publisher {
+	return multi(pubs)
+}
+
+func (m multi) Publish(ctx context.Context, ev *user_events.Event) error {
+	var errs []error
+	for _, p := range m {
+		if err := p.Publish(ctx, ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m multi) Close() error {
+	var errs []error
+	for _, p := range m {
+		errs = append(errs, p.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,127 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go_user_service/genproto/user_service"
+	"io"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatJSONL   = "jsonl"
+	FormatParquet = "parquet"
+)
+
+// UserWriter encodes users one by one. Close must be called to flush
+// buffered data and write any trailer the format needs.
+type UserWriter interface {
+	Write(*user_service.GetUser) error
+	Close() error
+}
+
+// NewUserWriter returns a writer for the given format, csv when empty.
+func NewUserWriter(format string, w io.Writer) (UserWriter, error) {
+	switch strings.ToLower(format) {
+	case "", FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: cw}, nil
+	case FormatJSONL:
+		return &jsonlWriter{enc: json.NewEncoder(w)}, nil
+	case FormatParquet:
+		return &parquetWriter{w: parquet.NewGenericWriter[parquetUser](w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// Extension returns the file extension used for the format.
+func Extension(format string) string {
+	switch strings.ToLower(format) {
+	case FormatJSONL:
+		return ".jsonl"
+	case FormatParquet:
+		return ".parquet"
+	default:
+		return ".csv"
+	}
+}
+
+var csvHeader = []string{"id", "user_login", "birthday", "gender", "fullname", "email", "phone", "created_at", "updated_at"}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Write(user *user_service.GetUser) error {
+	return c.w.Write([]string{
+		user.Id,
+		user.UserLogin,
+		user.Birthday,
+		user.Gender,
+		user.Fullname,
+		user.Email,
+		user.Phone,
+		user.CreatedAt,
+		user.UpdatedAt,
+	})
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter) Write(user *user_service.GetUser) error {
+	return j.enc.Encode(user)
+}
+
+func (j *jsonlWriter) Close() error {
+	return nil
+}
+
+type parquetUser struct {
+	Id        string `parquet:"id"`
+	UserLogin string `parquet:"user_login"`
+	Birthday  string `parquet:"birthday,optional"`
+	Gender    string `parquet:"gender,optional"`
+	Fullname  string `parquet:"fullname"`
+	Email     string `parquet:"email"`
+	Phone     string `parquet:"phone,optional"`
+	CreatedAt string `parquet:"created_at,optional"`
+	UpdatedAt string `parquet:"updated_at,optional"`
+}
+
+type parquetWriter struct {
+	w *parquet.GenericWriter[parquetUser]
+}
+
+func (p *parquetWriter) Write(user *user_service.GetUser) error {
+	_, err := p.w.Write([]parquetUser{{
+		Id:        user.Id,
+		UserLogin: user.UserLogin,
+		Birthday:  user.Birthday,
+		Gender:    user.Gender,
+		Fullname:  user.Fullname,
+		Email:     user.Email,
+		Phone:     user.Phone,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}})
+	return err
+}
+
+func (p *parquetWriter) Close() error {
+	return p.w.Close()
+}
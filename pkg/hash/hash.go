@@ -1,9 +1,16 @@
 package hash
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"context"
+	"go_user_service/pkg/tracing"
 
-func HashPassword(password string) (string, error) {
+	"golang.org/x/crypto/bcrypt"
+)
+
+func HashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Start(ctx, "bcrypt.GenerateFromPassword")
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	tracing.End(span, err)
 	if err != nil {
 		return "", err
 	}
@@ -11,6 +18,10 @@ func HashPassword(password string) (string, error) {
 	return string(hashedPassword), nil
 }
 
-func CompareHashAndPassword(hashedPassword, password string) error {
+func CompareHashAndPassword(ctx context.Context, hashedPassword, password string) error {
+	_, span := tracing.Start(ctx, "bcrypt.CompareHashAndPassword")
+	defer span.End()
+
+	// a mismatch is an expected outcome, so it isn't recorded as a span error
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
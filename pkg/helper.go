@@ -2,7 +2,6 @@ package pkg
 
 import (
 	"database/sql"
-	"math/rand"
 )
 
 func NullStringToString(s sql.NullString) string {
@@ -20,8 +19,3 @@ func NullTimeToString(s sql.NullTime) string {
 
 	return ""
 }
-
-func GenerateOTP() int {
-
-	return rand.Intn(900000) + 100000
-}
@@ -74,3 +74,17 @@ func ExtractClaims(tokenStr string) (jwt.MapClaims, error) {
 	}
 	return claims, nil
 }
+
+// UserID returns the user_id claim. RegisterConfirm used to put the whole
+// primary key in it, so tokens issued before carry {"id": "..."} instead of
+// the plain id that Login and RegisterConfirm write now.
+func UserID(claims jwt.MapClaims) (string, bool) {
+	switch id := claims["user_id"].(type) {
+	case string:
+		return id, id != ""
+	case map[string]interface{}:
+		s, ok := id["id"].(string)
+		return s, ok && s != ""
+	}
+	return "", false
+}
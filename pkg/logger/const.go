@@ -1,15 +1,18 @@
 package logger
 
-import (
-	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
+const (
+	LevelDebug  = "debug"
+	LevelInfo   = "info"
+	LevelWarn   = "warn"
+	LevelError  = "error"
+	LevelDPanic = "dpanic"
+	LevelPanic  = "panic"
+	LevelFatal  = "fatal"
 )
 
-type Field = zapcore.Field
-
-var (
-	Int    = zap.Int
-	String = zap.String
-	Error  = zap.Error
-	Any    = zap.Any
+const (
+	// FormatJSON writes one JSON object per line, for log collectors.
+	FormatJSON = "json"
+	// FormatConsole writes human readable lines, for local runs.
+	FormatConsole = "console"
 )
@@ -1,33 +1,107 @@
+// Package logger is the one logger used across the service. It wraps zap,
+// writes JSON or console lines, and carries a per request logger in the
+// context so every line of an RPC shares its request id.
 package logger
 
 import (
+	"context"
+
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type Field = zapcore.Field
+
+var (
+	Int      = zap.Int
+	Int64    = zap.Int64
+	String   = zap.String
+	Bool     = zap.Bool
+	Duration = zap.Duration
+	Error    = zap.Error
+	Any      = zap.Any
 )
 
-type ILogger interface {
+type LoggerI interface {
+	Debug(msg string, fields ...Field)
 	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
 	Error(msg string, fields ...Field)
-	Warning(msg string, fields ...Field)
+	DPanic(msg string, fields ...Field)
+	Panic(msg string, fields ...Field)
+	Fatal(msg string, fields ...Field)
+	// With returns a logger that adds fields to every line.
+	With(fields ...Field) LoggerI
+	// Sync flushes buffered lines, call it before exiting.
+	Sync() error
 }
 
 type logger struct {
 	zap *zap.Logger
 }
 
+// New returns a logger named namespace writing lines at level and above in
+// format, FormatJSON or FormatConsole.
+func New(namespace, level, format string) LoggerI {
+	return logger{zap: newZapLogger(namespace, level, format)}
+}
+
+// Nop discards everything, for tests and tools that don't log.
+func Nop() LoggerI {
+	return logger{zap: zap.NewNop()}
+}
+
+func (l logger) Debug(msg string, fields ...Field) {
+	l.zap.Debug(msg, fields...)
+}
+
 func (l logger) Info(msg string, fields ...Field) {
 	l.zap.Info(msg, fields...)
 }
 
+func (l logger) Warn(msg string, fields ...Field) {
+	l.zap.Warn(msg, fields...)
+}
+
 func (l logger) Error(msg string, fields ...Field) {
 	l.zap.Error(msg, fields...)
 }
 
-func (l logger) Warning(msg string, fields ...Field) {
-	l.zap.Warn(msg, fields...)
+func (l logger) DPanic(msg string, fields ...Field) {
+	l.zap.DPanic(msg, fields...)
+}
+
+func (l logger) Panic(msg string, fields ...Field) {
+	l.zap.Panic(msg, fields...)
+}
+
+func (l logger) Fatal(msg string, fields ...Field) {
+	l.zap.Fatal(msg, fields...)
+}
+
+func (l logger) With(fields ...Field) LoggerI {
+	return logger{zap: l.zap.With(fields...)}
 }
 
-func New(namespace string) ILogger {
-	return logger{
-		zap: newZapLogger(namespace),
+func (l logger) Sync() error {
+	return l.zap.Sync()
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying l.
+func NewContext(ctx context.Context, l LoggerI) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or fallback when there is
+// none, e.g. outside of an RPC. A nil fallback discards the lines.
+func FromContext(ctx context.Context, fallback LoggerI) LoggerI {
+	if l, ok := ctx.Value(ctxKey{}).(LoggerI); ok {
+		return l
+	}
+	if fallback == nil {
+		return Nop()
 	}
+	return fallback
 }
@@ -7,23 +7,39 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-func newZapLogger(namespace string) *zap.Logger {
-	stdout := zapcore.AddSync(os.Stdout)
-
-	level := zap.NewAtomicLevelAt(zap.InfoLevel)
-
-	productionCfg := zap.NewProductionEncoderConfig()
-	productionCfg.TimeKey = "timestamp"
-	productionCfg.EncodeTime = zapcore.ISO8601TimeEncoder
-
-	developmentCfg := zap.NewDevelopmentEncoderConfig()
-	developmentCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
-
-	consoleEncoder := zapcore.NewConsoleEncoder(developmentCfg)
-
-	core := zapcore.NewTee(zapcore.NewCore(consoleEncoder, stdout, level))
-
-	newZap := zap.New(core)
+func newZapLogger(namespace, level, format string) *zap.Logger {
+	encoderCfg := zap.NewProductionEncoderConfig()
+	encoderCfg.TimeKey = "timestamp"
+	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	var encoder zapcore.Encoder
+	if format == FormatJSON {
+		encoder = zapcore.NewJSONEncoder(encoderCfg)
+	} else {
+		encoderCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderCfg)
+	}
+
+	core := zapcore.NewCore(encoder, zapcore.Lock(os.Stdout), zap.NewAtomicLevelAt(parseLevel(level)))
+
+	return zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1)).Named(namespace)
+}
 
-	return newZap
+func parseLevel(level string) zapcore.Level {
+	switch level {
+	case LevelDebug:
+		return zapcore.DebugLevel
+	case LevelWarn:
+		return zapcore.WarnLevel
+	case LevelError:
+		return zapcore.ErrorLevel
+	case LevelDPanic:
+		return zapcore.DPanicLevel
+	case LevelPanic:
+		return zapcore.PanicLevel
+	case LevelFatal:
+		return zapcore.FatalLevel
+	default:
+		return zapcore.InfoLevel
+	}
 }
@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type fileDrop struct {
+	dir  string
+	from string
+	seq  atomic.Int64
+}
+
+// NewFileDrop writes every message as an .eml file into dir, so emails can be
+// opened in a mail client during development.
+func NewFileDrop(dir, from string) (Mailer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("MAIL_DROP_DIR is required for the file mail driver")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+	return &fileDrop{dir: dir, from: from}, nil
+}
+
+func (f *fileDrop) Send(ctx context.Context, msg Message) error {
+	body, err := buildMIME(f.from, msg)
+	if err != nil {
+		return err
+	}
+
+	to := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%s_%04d_%s.eml", time.Now().UTC().Format("20060102T150405"), f.seq.Add(1), to)
+
+	return os.WriteFile(filepath.Join(f.dir, name), body, 0o644)
+}
@@ -0,0 +1,44 @@
+// Package mailer renders the service's emails and hands them to a delivery
+// backend: SMTP in production, a drop directory or memory for dev and tests.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"go_user_service/config"
+)
+
+// Message is a rendered email ready to be sent.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers rendered messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer selected by cfg.MailDriver.
+func New(cfg config.Config) (Mailer, error) {
+	switch cfg.MailDriver {
+	case "", "smtp":
+		return NewSMTP(SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+			TLS:      cfg.SMTPTLS,
+			Timeout:  cfg.SMTPTimeout,
+		})
+	case "file":
+		return NewFileDrop(cfg.MailDropDir, cfg.SMTPFrom)
+	case "memory":
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.MailDriver)
+	}
+}
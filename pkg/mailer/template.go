@@ -0,0 +1,124 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Kind names a message type, it is also the base name of its template files.
+type Kind string
+
+const (
+	RegisterOTP   Kind = "register_otp"
+	PasswordReset Kind = "password_reset"
+	EmailChange   Kind = "email_change"
+	LoginCode     Kind = "login_code"
+)
+
+// DefaultLanguage is used when none of the requested languages has templates.
+const DefaultLanguage = "en"
+
+// Every message kind has templates/<lang>/<kind>.txt, which defines a
+// "subject" block next to the plain text body, and templates/<lang>/<kind>.html,
+// which fills the "content" block of templates/layout.html.
+//
+//go:embed templates
+var templateFS embed.FS
+
+// Data is what the templates can refer to.
+type Data struct {
+	// Code is the one-time code or token the message carries
+	Code string
+	// Service is the product name shown to the user
+	Service string
+	// Minutes is how long the code stays valid
+	Minutes int
+}
+
+// Render builds the message of the given kind in the first of langs that has
+// templates, falling back to DefaultLanguage.
+func Render(kind Kind, langs []string, to string, data Data) (Message, error) {
+	lang := pickLanguage(kind, langs)
+	base := "templates/" + lang + "/" + string(kind)
+
+	txt, err := texttemplate.ParseFS(templateFS, base+".txt")
+	if err != nil {
+		return Message{}, err
+	}
+	html, err := htmltemplate.ParseFS(templateFS, "templates/layout.html", base+".html")
+	if err != nil {
+		return Message{}, err
+	}
+
+	var subject, text, body bytes.Buffer
+	if err = txt.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("rendering %s subject: %w", base, err)
+	}
+	if err = txt.Execute(&text, data); err != nil {
+		return Message{}, fmt.Errorf("rendering %s text: %w", base, err)
+	}
+	if err = html.ExecuteTemplate(&body, "layout", data); err != nil {
+		return Message{}, fmt.Errorf("rendering %s html: %w", base, err)
+	}
+
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    body.String(),
+	}, nil
+}
+
+func pickLanguage(kind Kind, langs []string) string {
+	for _, lang := range langs {
+		lang = strings.ToLower(lang)
+		if i := strings.IndexAny(lang, "-_"); i > 0 {
+			lang = lang[:i]
+		}
+		if _, err := templateFS.Open("templates/" + lang + "/" + string(kind) + ".txt"); err == nil {
+			return lang
+		}
+	}
+	return DefaultLanguage
+}
+
+// ParseAcceptLanguage returns the language tags of an Accept-Language value
+// ordered by preference.
+func ParseAcceptLanguage(header string) []string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if fields[0] == "" || fields[0] == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if _, err := fmt.Sscanf(param[2:], "%g", &q); err != nil {
+					q = 0
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{lang: fields[0], q: q})
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	langs := make([]string, len(tags))
+	for i, t := range tags {
+		langs[i] = t.lang
+	}
+	return langs
+}
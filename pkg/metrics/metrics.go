@@ -0,0 +1,115 @@
+// Package metrics holds the prometheus collectors of the service. They are
+// all registered on Registry, which Handler serves on /metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "user_service"
+
+const (
+	AccountUser  = "user"
+	AccountAdmin = "admin"
+)
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by account type and result.",
+	}, []string{"account", "result"})
+
+	registrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Registrations by account type and stage, started when the code is sent and confirmed when the account is created.",
+	}, []string{"account", "stage"})
+
+	emails = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "emails_total",
+		Help:      "Email delivery attempts by template and result.",
+	}, []string{"kind", "result"})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "RPCs rejected by the rate limiter, by method.",
+	}, []string{"method"})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Read-through cache lookups of GetById by account type and result.",
+	}, []string{"account", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		logins,
+		registrations,
+		emails,
+		rateLimited,
+		cacheLookups,
+		rpcHandled,
+		rpcDuration,
+		redisDuration,
+		redisErrors,
+	)
+}
+
+// Handler serves everything registered on Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+func result(err error, ok, failed string) string {
+	if err != nil {
+		return failed
+	}
+	return ok
+}
+
+// Login counts a login attempt, err is what the login returned.
+func Login(account string, err error) {
+	logins.WithLabelValues(account, result(err, "succeeded", "failed")).Inc()
+}
+
+// RegistrationStarted counts a register code that was sent out.
+func RegistrationStarted(account string) {
+	registrations.WithLabelValues(account, "started").Inc()
+}
+
+// RegistrationConfirmed counts an account created from a register code.
+func RegistrationConfirmed(account string) {
+	registrations.WithLabelValues(account, "confirmed").Inc()
+}
+
+// Email counts one attempt to send an email of kind.
+func Email(kind string, err error) {
+	emails.WithLabelValues(kind, result(err, "sent", "failed")).Inc()
+}
+
+// RateLimited counts a call to method that was rejected for exceeding its
+// rate limit.
+func RateLimited(method string) {
+	rateLimited.WithLabelValues(method).Inc()
+}
+
+// CacheLookup counts a cache lookup for an account, a hit or a miss.
+func CacheLookup(account string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(account, result).Inc()
+}
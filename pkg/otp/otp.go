@@ -0,0 +1,54 @@
+// Package otp generates one-time codes and tokens from crypto/rand.
+package otp
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+const (
+	digits       = "0123456789"
+	alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// Numeric returns a code of length decimal digits, leading zeros included.
+func Numeric(length int) (string, error) {
+	return generate(digits, length)
+}
+
+// Token returns a random string of length letters and digits, for invite and
+// reset links.
+func Token(length int) (string, error) {
+	return generate(alphanumeric, length)
+}
+
+// generate picks each character uniformly from alphabet. Random bytes that
+// fall into the incomplete block at the top of the byte range are thrown
+// away, otherwise the first 256%len(alphabet) characters would come up more
+// often than the rest.
+func generate(alphabet string, length int) (string, error) {
+	if length <= 0 {
+		return "", errors.New("otp length must be positive")
+	}
+
+	limit := 256 - 256%len(alphabet)
+	out := make([]byte, 0, length)
+	buf := make([]byte, length)
+
+	for len(out) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			out = append(out, alphabet[int(b)%len(alphabet)])
+			if len(out) == length {
+				break
+			}
+		}
+	}
+
+	return string(out), nil
+}
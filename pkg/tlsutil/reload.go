@@ -0,0 +1,100 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// reloader holds a value loaded from files and loads it again when any of
+// the files changes. Files are checked at most once per interval, on use, so
+// there is nothing to start or stop. If a reload fails the last good value is
+// kept, which covers a cert and key being replaced one after the other.
+type reloader[T any] struct {
+	files    []string
+	load     func() (T, error)
+	interval time.Duration
+
+	mu      sync.Mutex
+	value   T
+	modTime time.Time
+	checked time.Time
+}
+
+func newReloader[T any](interval time.Duration, load func() (T, error), files ...string) (*reloader[T], error) {
+	r := &reloader[T]{files: files, load: load, interval: interval}
+
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return nil, err
+	}
+	if r.value, err = load(); err != nil {
+		return nil, err
+	}
+	r.modTime, r.checked = modTime, time.Now()
+	return r, nil
+}
+
+func (r *reloader[T]) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range r.files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (r *reloader[T]) get() T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) < r.interval {
+		return r.value
+	}
+	r.checked = time.Now()
+
+	modTime, err := r.latestModTime()
+	if err != nil || modTime.Equal(r.modTime) {
+		return r.value
+	}
+	if value, err := r.load(); err == nil {
+		r.value, r.modTime = value, modTime
+	}
+	return r.value
+}
+
+func newKeyPair(certFile, keyFile string, interval time.Duration) (*reloader[*tls.Certificate], error) {
+	return newReloader(interval, func() (*tls.Certificate, error) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return &cert, nil
+	}, certFile, keyFile)
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in " + file)
+	}
+	return pool, nil
+}
+
+func newCertPool(file string, interval time.Duration) (*reloader[*x509.CertPool], error) {
+	return newReloader(interval, func() (*x509.CertPool, error) {
+		return loadCertPool(file)
+	}, file)
+}
@@ -0,0 +1,90 @@
+// Package tlsutil builds the TLS settings of the gRPC server and of the
+// clients that call other services. Certificates and the client CA are
+// reloaded when their files change, so rotating them needs no restart.
+package tlsutil
+
+import (
+	"crypto/tls"
+	"fmt"
+	"go_user_service/config"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ServerCredentials returns the transport credentials for the gRPC server,
+// or nil when GRPC_TLS_CERT_FILE is not set and the server stays plaintext.
+// Setting GRPC_TLS_CLIENT_CA_FILE turns on mutual TLS: clients must then
+// present a certificate signed by that CA.
+func ServerCredentials(cfg config.Config) (credentials.TransportCredentials, error) {
+	if cfg.GRPCTLSCertFile == "" {
+		return nil, nil
+	}
+
+	pair, err := newKeyPair(cfg.GRPCTLSCertFile, cfg.GRPCTLSKeyFile, cfg.TLSReloadInterval)
+	if err != nil {
+		return nil, fmt.Errorf("grpc server certificate: %w", err)
+	}
+
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2"},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return pair.get(), nil
+		},
+	}
+	if cfg.GRPCTLSClientCAFile == "" {
+		return credentials.NewTLS(base), nil
+	}
+
+	clientCAs, err := newCertPool(cfg.GRPCTLSClientCAFile, cfg.TLSReloadInterval)
+	if err != nil {
+		return nil, fmt.Errorf("grpc client ca: %w", err)
+	}
+
+	// the client CA is picked per handshake so a reloaded pool applies to
+	// new connections right away
+	conf := base.Clone()
+	conf.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := base.Clone()
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+		c.ClientCAs = clientCAs.get()
+		return c, nil
+	}
+	return credentials.NewTLS(conf), nil
+}
+
+// ClientCredentials returns the transport credentials for calls to other
+// gRPC services, plaintext unless useTLS is set. GRPC_CLIENT_CA_FILE
+// replaces the system roots and GRPC_CLIENT_CERT_FILE/KEY_FILE are presented
+// to servers that require mutual TLS.
+func ClientCredentials(cfg config.Config, useTLS bool) (credentials.TransportCredentials, error) {
+	if !useTLS {
+		return insecure.NewCredentials(), nil
+	}
+
+	conf := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: cfg.GRPCClientServerName,
+	}
+
+	if cfg.GRPCClientCAFile != "" {
+		roots, err := loadCertPool(cfg.GRPCClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("grpc client root ca: %w", err)
+		}
+		conf.RootCAs = roots
+	}
+
+	if cfg.GRPCClientCertFile != "" {
+		pair, err := newKeyPair(cfg.GRPCClientCertFile, cfg.GRPCClientKeyFile, cfg.TLSReloadInterval)
+		if err != nil {
+			return nil, fmt.Errorf("grpc client certificate: %w", err)
+		}
+		conf.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return pair.get(), nil
+		}
+	}
+
+	return credentials.NewTLS(conf), nil
+}
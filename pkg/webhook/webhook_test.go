@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSendSignsRequest(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"id":"event-1","type":"user_created"}`)
+
+	var got *http.Request
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	res := NewSender(time.Second).Send(context.Background(), srv.URL, secret, "event-1", "user_created", body)
+	if res.Err != nil || res.StatusCode != http.StatusNoContent {
+		t.Fatalf("Send returned %+v, want 204 without error", res)
+	}
+
+	if got.Method != http.MethodPost || got.Header.Get(HeaderID) != "event-1" || got.Header.Get(HeaderEvent) != "user_created" {
+		t.Fatalf("request is %s with id %q and event %q", got.Method, got.Header.Get(HeaderID), got.Header.Get(HeaderEvent))
+	}
+	timestamp, err := strconv.ParseInt(got.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("%s: %v", HeaderTimestamp, err)
+	}
+	signature := got.Header.Get(HeaderSignature)
+	if !Verify(secret, timestamp, gotBody, signature) {
+		t.Fatalf("signature %q doesn't verify", signature)
+	}
+	if Verify("other", timestamp, gotBody, signature) || Verify(secret, timestamp, append(gotBody, ' '), signature) || Verify(secret, timestamp+1, gotBody, signature) {
+		t.Fatal("signature verifies with another secret, body or timestamp")
+	}
+}
+
+func TestSendFailsOnNon2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	res := NewSender(time.Second).Send(context.Background(), srv.URL, "secret", "event-1", "user_created", []byte(`{}`))
+	if res.Err == nil || res.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Send returned %+v, want 500 with an error", res)
+	}
+}
+
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	var hits int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer target.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer srv.Close()
+
+	res := NewSender(time.Second).Send(context.Background(), srv.URL, "secret", "event-1", "user_created", []byte(`{}`))
+	if res.Err == nil || res.StatusCode != http.StatusTemporaryRedirect {
+		t.Fatalf("Send returned %+v, want 307 with an error", res)
+	}
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Fatalf("redirect target got %d requests, want none", n)
+	}
+}
@@ -0,0 +1,165 @@
+// Package reconcile repairs state that other services keep about our users
+// when a change did not reach them, e.g. tasks created for a user while
+// their deletion was being carried over.
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go_user_service/genproto/task_service"
+	"go_user_service/genproto/user_service"
+	"go_user_service/outbox"
+	"go_user_service/pkg/logger"
+	"go_user_service/storage"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	taskPageSize   = 100
+	outboxPageSize = 100
+)
+
+// Tasks finds tasks whose owner no longer passes Check and queues a
+// task_cascade for the owner, so the usual outbox delivery and its retries
+// clean them up: a delete for a deleted owner and a purge for one that is
+// gone. Owners that already have a cascade pending are left to it.
+type Tasks struct {
+	strg     storage.StorageI
+	tasks    task_service.TaskServiceClient
+	log      logger.LoggerI
+	interval time.Duration
+}
+
+func NewTasks(strg storage.StorageI, tasks task_service.TaskServiceClient, log logger.LoggerI, interval time.Duration) *Tasks {
+	return &Tasks{
+		strg:     strg,
+		tasks:    tasks,
+		log:      log,
+		interval: interval,
+	}
+}
+
+// Run reconciles every interval until ctx is cancelled.
+func (t *Tasks) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := t.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			t.log.Error("---TaskReconcile--->>>", logger.Error(err))
+			continue
+		}
+		if n > 0 {
+			t.log.Info("---TaskReconcile--->>>", logger.Int("orphaned_owners", n))
+		}
+	}
+}
+
+// RunOnce walks all tasks once and returns the number of owners whose tasks
+// were queued for deletion.
+func (t *Tasks) RunOnce(ctx context.Context) (int, error) {
+	owners, err := t.owners(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending, err := t.pendingCascades(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var orphaned int
+	for _, owner := range owners {
+		if pending[owner] {
+			continue
+		}
+		active, err := t.strg.User().Check(ctx, &user_service.UserPrimaryKey{Id: owner})
+		if err != nil {
+			return orphaned, err
+		}
+		if active.Check {
+			continue
+		}
+
+		action := outbox.TaskCascadeDelete
+		_, err = t.strg.User().GetPersonalData(ctx, &user_service.UserPrimaryKey{Id: owner})
+		if errors.Is(err, pgx.ErrNoRows) {
+			action = outbox.TaskCascadePurge
+		} else if err != nil {
+			return orphaned, err
+		}
+
+		msg, err := outbox.NewTaskCascade(owner, action)
+		if err != nil {
+			return orphaned, err
+		}
+		if err = t.strg.Outbox().Add(ctx, msg); err != nil {
+			return orphaned, err
+		}
+		orphaned++
+	}
+	return orphaned, nil
+}
+
+// owners lists the distinct owners of the tasks the task service returns.
+func (t *Tasks) owners(ctx context.Context) ([]string, error) {
+	var (
+		owners []string
+		seen   = make(map[string]bool)
+	)
+	for offset := int64(1); ; offset++ {
+		resp, err := t.tasks.GetList(ctx, &task_service.GetListTaskRequest{
+			Offset: offset,
+			Limit:  taskPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, task := range resp.Tasks {
+			if task.UserId != "" && !seen[task.UserId] {
+				seen[task.UserId] = true
+				owners = append(owners, task.UserId)
+			}
+		}
+		if len(resp.Tasks) < taskPageSize {
+			return owners, nil
+		}
+	}
+}
+
+// pendingCascades returns the users that have a task_cascade message waiting
+// in the outbox, including ones being retried.
+func (t *Tasks) pendingCascades(ctx context.Context) (map[string]bool, error) {
+	pending := make(map[string]bool)
+	for offset := int64(1); ; offset++ {
+		msgs, _, err := t.strg.Outbox().List(ctx, &storage.OutboxListRequest{
+			Offset: offset,
+			Limit:  outboxPageSize,
+			Status: storage.OutboxPending,
+			Kind:   outbox.KindTaskCascade,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, msg := range msgs {
+			var cascade outbox.TaskCascade
+			if err := json.Unmarshal(msg.Payload, &cascade); err == nil {
+				pending[cascade.UserId] = true
+			}
+		}
+		if len(msgs) < outboxPageSize {
+			return pending, nil
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"go_user_service/config"
+	"go_user_service/genproto/task_service"
+	"go_user_service/genproto/user_service"
+	"go_user_service/outbox"
+	"go_user_service/pkg/logger"
+	"go_user_service/storage"
+	"go_user_service/storage/memory"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+)
+
+// listTasks is a task service that only answers GetList, with one task per
+// owner.
+type listTasks struct {
+	task_service.TaskServiceClient
+	owners []string
+}
+
+func (l *listTasks) GetList(ctx context.Context, req *task_service.GetListTaskRequest, opts ...grpc.CallOption) (*task_service.GetListTaskResponse, error) {
+	resp := &task_service.GetListTaskResponse{}
+	if req.Offset == 1 {
+		for _, owner := range l.owners {
+			resp.Tasks = append(resp.Tasks, &task_service.GetListTask{Id: uuid.NewString(), UserId: owner})
+		}
+	}
+	resp.Count = int64(len(resp.Tasks))
+	return resp, nil
+}
+
+func createUser(t *testing.T, strg storage.StorageI, email string) string {
+	t.Helper()
+	user, err := strg.User().Create(context.Background(), &user_service.CreateUser{
+		Gender:       "male",
+		Fullname:     "Task Owner",
+		Email:        email,
+		UserPassword: "secret",
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return user.Id
+}
+
+func TestRunOnce(t *testing.T) {
+	ctx := context.Background()
+	strg := memory.New(memory.NewRedis(config.Load()))
+
+	active := createUser(t, strg, "active.owner@gmail.com")
+	deleted := createUser(t, strg, "deleted.owner@gmail.com")
+	if _, err := strg.User().Delete(ctx, &user_service.UserPrimaryKey{Id: deleted}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	purged := uuid.NewString()
+
+	r := NewTasks(strg, &listTasks{owners: []string{active, deleted, purged}}, logger.Nop(), time.Hour)
+
+	n, err := r.RunOnce(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("RunOnce returned %d, %v, want 2 orphaned owners", n, err)
+	}
+
+	msgs, _, err := strg.Outbox().List(ctx, &storage.OutboxListRequest{Offset: 1, Limit: 10, Kind: outbox.KindTaskCascade})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	actions := make(map[string]string)
+	for _, msg := range msgs {
+		var cascade outbox.TaskCascade
+		if err := json.Unmarshal(msg.Payload, &cascade); err != nil {
+			t.Fatalf("payload %s: %v", msg.Payload, err)
+		}
+		actions[cascade.UserId] = cascade.Action
+	}
+	if len(msgs) != 2 || actions[deleted] != outbox.TaskCascadeDelete || actions[purged] != outbox.TaskCascadePurge {
+		t.Fatalf("queued %v, want a delete for the deleted owner and a purge for the purged one", actions)
+	}
+
+	// the cascades are still pending, so nothing is queued again
+	if n, err = r.RunOnce(ctx); err != nil || n != 0 {
+		t.Fatalf("second RunOnce returned %d, %v, want 0", n, err)
+	}
+	if _, count, _ := strg.Outbox().List(ctx, &storage.OutboxListRequest{Offset: 1, Limit: 10, Kind: outbox.KindTaskCascade}); count != 2 {
+		t.Fatalf("outbox holds %d cascades after the second run, want 2", count)
+	}
+}
@@ -6,29 +6,47 @@ CURRENT_DIR=$1
 # Удаляем старые сгенерированные файлы
 rm -rf "${CURRENT_DIR}/genproto/*"
 
-# Устанавливаем пути к плагинам
+# Устанавливаем пути к плагинам. genproto и api/openapi сгенерированы версиями:
+#   protoc                  v3.12.4
+#   protoc-gen-go           v1.28.1  (google.golang.org/protobuf/cmd/protoc-gen-go)
+#   protoc-gen-go-grpc      v1.2.0   (google.golang.org/grpc/cmd/protoc-gen-go-grpc)
+#   protoc-gen-grpc-gateway v2.20.0  (github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-grpc-gateway)
+#   protoc-gen-openapiv2    v2.20.0  (github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2)
 PROTOC_GEN_GO=$(which protoc-gen-go)
 PROTOC_GEN_GO_GRPC=$(which protoc-gen-go-grpc)
+PROTOC_GEN_GATEWAY=$(which protoc-gen-grpc-gateway)
+PROTOC_GEN_OPENAPI=$(which protoc-gen-openapiv2)
 
 # Проверяем, что плагины найдены
 if [[ -z "$PROTOC_GEN_GO" || -z "$PROTOC_GEN_GO_GRPC" ]]; then
   echo "protoc-gen-go or protoc-gen-go-grpc not found in PATH"
   exit 1
 fi
+if [[ -z "$PROTOC_GEN_GATEWAY" || -z "$PROTOC_GEN_OPENAPI" ]]; then
+  echo "protoc-gen-grpc-gateway or protoc-gen-openapiv2 not found in PATH"
+  exit 1
+fi
+
+rm -f "${CURRENT_DIR}"/api/openapi/*.json
+mkdir -p "${CURRENT_DIR}/api/openapi"
 
 # Генерация кода для каждого подкаталога в папке protos
 for x in $(find ${CURRENT_DIR}/to_do_list_proto/* -type d); do
   protoc --plugin="protoc-gen-go=${PROTOC_GEN_GO}" \
          --plugin="protoc-gen-go-grpc=${PROTOC_GEN_GO_GRPC}" \
-         -I=${x} -I=${CURRENT_DIR}/to_do_list_proto -I /usr/local/include \
+         -I=${x} -I=${CURRENT_DIR}/to_do_list_proto -I=${CURRENT_DIR}/third_party -I /usr/local/include \
          --go_out=${CURRENT_DIR} \
          --go-grpc_out=require_unimplemented_servers=false:${CURRENT_DIR} \
          ${x}/*.proto
-done
-
-
-
-
-
-
 
+  # HTTP gateway и OpenAPI только для сервисов с аннотациями google.api.http
+  annotated=$(grep -l 'import "google/api/annotations.proto"' ${x}/*.proto)
+  if [[ -n "$annotated" ]]; then
+    protoc --plugin="protoc-gen-grpc-gateway=${PROTOC_GEN_GATEWAY}" \
+           --plugin="protoc-gen-openapiv2=${PROTOC_GEN_OPENAPI}" \
+           -I=${x} -I=${CURRENT_DIR}/to_do_list_proto -I=${CURRENT_DIR}/third_party -I /usr/local/include \
+           --grpc-gateway_out=generate_unbound_methods=false:${CURRENT_DIR} \
+           --openapiv2_out=${CURRENT_DIR}/api/openapi \
+           ${annotated}
+  fi
+done
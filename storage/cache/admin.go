@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"context"
+	"go_user_service/genproto/admin_service"
+	"go_user_service/pkg/metrics"
+	"go_user_service/storage"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type adminRepo struct {
+	storage.AdminRepoI
+	s *Store
+}
+
+func adminKey(id string) string {
+	return "cache:admin:" + id
+}
+
+func (c *adminRepo) GetById(ctx context.Context, id *admin_service.AdminPrimaryKey) (*admin_service.GetAdmin, error) {
+	admin := &admin_service.GetAdmin{}
+	err := c.s.get(ctx, metrics.AccountAdmin, adminKey(id.Id), admin, func(ctx context.Context) (proto.Message, error) {
+		return c.AdminRepoI.GetById(ctx, id)
+	})
+	return admin, err
+}
+
+func (c *adminRepo) Update(ctx context.Context, req *admin_service.UpdateAdmin) (*admin_service.GetAdmin, error) {
+	defer c.s.invalidate(ctx, adminKey(req.Id))
+	return c.AdminRepoI.Update(ctx, req)
+}
+
+func (c *adminRepo) Delete(ctx context.Context, id *admin_service.AdminPrimaryKey) (emptypb.Empty, error) {
+	defer c.s.invalidate(ctx, adminKey(id.Id))
+	return c.AdminRepoI.Delete(ctx, id)
+}
+
+func (c *adminRepo) ChangePassword(ctx context.Context, pass *admin_service.AdminChangePassword) (*admin_service.AdminChangePasswordResp, error) {
+	resp, err := c.AdminRepoI.ChangePassword(ctx, pass)
+	if err != nil {
+		return resp, err
+	}
+
+	if admin, err := c.AdminRepoI.GetByLogin(ctx, pass.UserLogin); err == nil {
+		c.s.invalidate(ctx, adminKey(admin.Id))
+	}
+	return resp, nil
+}
@@ -0,0 +1,144 @@
+// Package cache is a read-through cache for GetById and Check of the user
+// and admin repos. Serialized GetUser/GetAdmin protos are kept in redis
+// with a TTL and replaced by a short lived tombstone on every write that
+// changes them.
+package cache
+
+import (
+	"context"
+	"go_user_service/pkg/metrics"
+	"go_user_service/storage"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/protobuf/proto"
+)
+
+// tombstoneTTL is how long a dropped key stays blocked. A miss only fills
+// an empty key, so a load that read the row before a write can't put the
+// old value back unless it takes longer than this.
+const tombstoneTTL = 10 * time.Second
+
+// loadTimeout bounds a shared load on a miss. The load doesn't use the
+// context of the caller that started it, so that caller going away doesn't
+// fail the others waiting for the same key.
+const loadTimeout = 5 * time.Second
+
+type cache struct {
+	redis storage.IRedisStorage
+	ttl   time.Duration
+	group singleflight.Group
+}
+
+type Store struct {
+	storage.StorageI
+	c *cache
+	// tx is set while running inside WithTx, see txState
+	tx *txState
+}
+
+// txState collects the keys written inside a transaction. Reads inside a
+// transaction skip the cache, and the keys are dropped again after commit
+// so a concurrent read can't keep a value from before the commit.
+type txState struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func New(strg storage.StorageI, redis storage.IRedisStorage, ttl time.Duration) *Store {
+	return &Store{
+		StorageI: strg,
+		c: &cache{
+			redis: redis,
+			ttl:   ttl,
+		},
+	}
+}
+
+func (s *Store) User() storage.UserRepoI {
+	return &userRepo{UserRepoI: s.StorageI.User(), s: s}
+}
+
+func (s *Store) Admin() storage.AdminRepoI {
+	return &adminRepo{AdminRepoI: s.StorageI.Admin(), s: s}
+}
+
+func (s *Store) WithTx(ctx context.Context, fn func(storage.StorageI) error) error {
+	if s.tx != nil {
+		return s.StorageI.WithTx(ctx, func(tx storage.StorageI) error {
+			return fn(&Store{StorageI: tx, c: s.c, tx: s.tx})
+		})
+	}
+
+	state := &txState{}
+	err := s.StorageI.WithTx(ctx, func(tx storage.StorageI) error {
+		return fn(&Store{StorageI: tx, c: s.c, tx: state})
+	})
+	s.c.del(ctx, state.keys...)
+	return err
+}
+
+// get loads key into msg from redis, or calls load on a miss and caches the
+// result. Concurrent misses for the same key share a single load, which
+// runs with loadTimeout instead of the deadline of any one caller. Lookups
+// are counted per account in metrics.
+func (s *Store) get(ctx context.Context, account, key string, msg proto.Message, load func(context.Context) (proto.Message, error)) error {
+	if s.tx != nil {
+		loaded, err := load(ctx)
+		if err != nil {
+			return err
+		}
+		proto.Merge(msg, loaded)
+		return nil
+	}
+
+	// an empty value is a tombstone left by invalidate
+	if val, err := s.c.redis.Get(ctx, key); err == nil && val != "" && proto.Unmarshal([]byte(val), msg) == nil {
+		metrics.CacheLookup(account, true)
+		return nil
+	}
+	metrics.CacheLookup(account, false)
+
+	ch := s.c.group.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+
+		loaded, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if data, err := proto.Marshal(loaded); err == nil {
+			_, _ = s.c.redis.SetNX(ctx, key, string(data), s.c.ttl)
+		}
+		return loaded, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return res.Err
+		}
+		proto.Merge(msg, res.Val.(proto.Message))
+		return nil
+	}
+}
+
+// invalidate drops keys now and, inside a transaction, again after commit.
+// Each key is left holding a tombstone, see tombstoneTTL.
+func (s *Store) invalidate(ctx context.Context, keys ...string) {
+	if s.tx != nil {
+		s.tx.mu.Lock()
+		s.tx.keys = append(s.tx.keys, keys...)
+		s.tx.mu.Unlock()
+	}
+	s.c.del(ctx, keys...)
+}
+
+func (c *cache) del(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		_ = c.redis.SetX(ctx, key, "", tombstoneTTL)
+	}
+}
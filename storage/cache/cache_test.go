@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"context"
+	"go_user_service/config"
+	"go_user_service/genproto/user_service"
+	"go_user_service/storage"
+	"go_user_service/storage/memory"
+	"go_user_service/storage/storagetest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.RunStorage(t, func(t *testing.T) storage.StorageI {
+		redis := memory.NewRedis(config.Load())
+		return New(memory.New(redis), redis, time.Minute)
+	})
+}
+
+// pausedStore holds GetById after it has read the row, until release is
+// closed. A GetById whose context is done by then returns its error.
+type pausedStore struct {
+	storage.StorageI
+	once    sync.Once
+	loaded  chan struct{}
+	release chan struct{}
+}
+
+func (s *pausedStore) User() storage.UserRepoI {
+	return &pausedUserRepo{UserRepoI: s.StorageI.User(), s: s}
+}
+
+type pausedUserRepo struct {
+	storage.UserRepoI
+	s *pausedStore
+}
+
+func (r *pausedUserRepo) GetById(ctx context.Context, id *user_service.UserPrimaryKey) (*user_service.GetUser, error) {
+	user, err := r.UserRepoI.GetById(ctx, id)
+	r.s.once.Do(func() { close(r.s.loaded) })
+	<-r.s.release
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return user, err
+}
+
+func TestStaleFillAfterWrite(t *testing.T) {
+	ctx := context.Background()
+	redis := memory.NewRedis(config.Load())
+	inner := memory.New(redis)
+	created, err := inner.User().Create(ctx, &user_service.CreateUser{
+		Gender:       "male",
+		Fullname:     "Before",
+		Email:        "stale.fill@gmail.com",
+		UserPassword: "secret",
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	paused := &pausedStore{StorageI: inner, loaded: make(chan struct{}), release: make(chan struct{})}
+	strg := New(paused, redis, time.Minute)
+	key := &user_service.UserPrimaryKey{Id: created.Id}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = strg.User().GetById(ctx, key)
+	}()
+	<-paused.loaded
+
+	// the write commits while the miss above still holds the old row
+	_, err = New(inner, redis, time.Minute).User().Update(ctx, &user_service.UpdateUser{
+		Id:       created.Id,
+		Gender:   "male",
+		Fullname: "After",
+		Email:    created.Email,
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	close(paused.release)
+	<-done
+
+	got, err := New(inner, redis, time.Minute).User().GetById(ctx, key)
+	if err != nil {
+		t.Fatalf("GetById: %v", err)
+	}
+	if got.Fullname != "After" {
+		t.Fatalf("GetById returned %q, the miss cached the row from before the write", got.Fullname)
+	}
+}
+
+func TestLoadOutlivesCanceledCaller(t *testing.T) {
+	redis := memory.NewRedis(config.Load())
+	inner := memory.New(redis)
+	created, err := inner.User().Create(context.Background(), &user_service.CreateUser{
+		Gender:       "female",
+		Fullname:     "Shared Load",
+		Email:        "shared.load@gmail.com",
+		UserPassword: "secret",
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	paused := &pausedStore{StorageI: inner, loaded: make(chan struct{}), release: make(chan struct{})}
+	strg := New(paused, redis, time.Minute)
+	key := &user_service.UserPrimaryKey{Id: created.Id}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := strg.User().GetById(ctx, key)
+		first <- err
+	}()
+	<-paused.loaded
+
+	type result struct {
+		user *user_service.GetUser
+		err  error
+	}
+	second := make(chan result, 1)
+	go func() {
+		user, err := strg.User().GetById(context.Background(), key)
+		second <- result{user, err}
+	}()
+
+	// the caller that started the load goes away, the one waiting on the
+	// same key must still get the row
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Fatalf("canceled GetById returned %v, want context.Canceled", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(paused.release)
+
+	res := <-second
+	if res.err != nil || res.user.Fullname != "Shared Load" {
+		t.Fatalf("waiting GetById returned %v, %v", res.user, res.err)
+	}
+}
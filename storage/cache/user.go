@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"go_user_service/genproto/user_service"
+	"go_user_service/pkg/metrics"
+	"go_user_service/storage"
+
+	"github.com/jackc/pgx/v4"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type userRepo struct {
+	storage.UserRepoI
+	s *Store
+}
+
+func userKey(id string) string {
+	return "cache:user:" + id
+}
+
+func (c *userRepo) GetById(ctx context.Context, id *user_service.UserPrimaryKey) (*user_service.GetUser, error) {
+	user := &user_service.GetUser{}
+	err := c.s.get(ctx, metrics.AccountUser, userKey(id.Id), user, func(ctx context.Context) (proto.Message, error) {
+		return c.UserRepoI.GetById(ctx, id)
+	})
+	return user, err
+}
+
+// Check answers from the cached user when there is one. A user that is not
+// found is not cached, so a restored or newly created user is seen at once.
+func (c *userRepo) Check(ctx context.Context, id *user_service.UserPrimaryKey) (*user_service.CheckUserResp, error) {
+	_, err := c.GetById(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &user_service.CheckUserResp{Check: false}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user_service.CheckUserResp{Check: true}, nil
+}
+
+func (c *userRepo) Update(ctx context.Context, req *user_service.UpdateUser) (*user_service.GetUser, error) {
+	defer c.s.invalidate(ctx, userKey(req.Id))
+	return c.UserRepoI.Update(ctx, req)
+}
+
+func (c *userRepo) Delete(ctx context.Context, id *user_service.UserPrimaryKey) (emptypb.Empty, error) {
+	defer c.s.invalidate(ctx, userKey(id.Id))
+	return c.UserRepoI.Delete(ctx, id)
+}
+
+func (c *userRepo) Restore(ctx context.Context, id *user_service.UserPrimaryKey) (emptypb.Empty, error) {
+	defer c.s.invalidate(ctx, userKey(id.Id))
+	return c.UserRepoI.Restore(ctx, id)
+}
+
+func (c *userRepo) Purge(ctx context.Context, id *user_service.UserPrimaryKey) (emptypb.Empty, error) {
+	defer c.s.invalidate(ctx, userKey(id.Id))
+	return c.UserRepoI.Purge(ctx, id)
+}
+
+func (c *userRepo) SetPassword(ctx context.Context, id, password string) error {
+	defer c.s.invalidate(ctx, userKey(id))
+	return c.UserRepoI.SetPassword(ctx, id, password)
+}
+
+func (c *userRepo) ChangePassword(ctx context.Context, pass *user_service.UserChangePassword) (*user_service.UserChangePasswordResp, error) {
+	resp, err := c.UserRepoI.ChangePassword(ctx, pass)
+	if err != nil {
+		return resp, err
+	}
+
+	if user, err := c.UserRepoI.GetByLogin(ctx, pass.UserLogin); err == nil {
+		c.s.invalidate(ctx, userKey(user.Id))
+	}
+	return resp, nil
+}
@@ -0,0 +1,84 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"go_user_service/genproto/admin_service"
+	"go_user_service/genproto/user_events"
+	"go_user_service/pkg/events"
+	"go_user_service/storage"
+
+	"github.com/jackc/pgx/v4"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type adminRepo struct {
+	storage.AdminRepoI
+	s *Store
+}
+
+func (c *adminRepo) Create(ctx context.Context, req *admin_service.CreateAdmin) (admin *admin_service.GetAdmin, err error) {
+	err = c.s.record(ctx, func(tx storage.StorageI) ([]*user_events.Event, error) {
+		admin, err = tx.Admin().Create(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return []*user_events.Event{events.AdminCreated(&user_events.AdminCreated{
+			AdminId:   admin.Id,
+			UserLogin: admin.UserLogin,
+			Email:     admin.Email,
+			Fullname:  admin.Fullname,
+		})}, nil
+	})
+	return admin, err
+}
+
+func (c *adminRepo) Update(ctx context.Context, req *admin_service.UpdateAdmin) (admin *admin_service.GetAdmin, err error) {
+	err = c.s.record(ctx, func(tx storage.StorageI) ([]*user_events.Event, error) {
+		admin, err = tx.Admin().Update(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return []*user_events.Event{events.AdminUpdated(&user_events.AdminUpdated{
+			AdminId:   admin.Id,
+			UserLogin: admin.UserLogin,
+			Email:     admin.Email,
+			Fullname:  admin.Fullname,
+		})}, nil
+	})
+	return admin, err
+}
+
+// Delete only records an event when an active admin was actually deleted.
+func (c *adminRepo) Delete(ctx context.Context, id *admin_service.AdminPrimaryKey) (resp emptypb.Empty, err error) {
+	err = c.s.record(ctx, func(tx storage.StorageI) ([]*user_events.Event, error) {
+		_, err := tx.Admin().GetById(ctx, id)
+		active := err == nil
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		if _, err = tx.Admin().Delete(ctx, id); err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, nil
+		}
+		return []*user_events.Event{events.AdminDeleted(id.Id)}, nil
+	})
+	return emptypb.Empty{}, err
+}
+
+func (c *adminRepo) ChangePassword(ctx context.Context, pass *admin_service.AdminChangePassword) (resp *admin_service.AdminChangePasswordResp, err error) {
+	err = c.s.record(ctx, func(tx storage.StorageI) ([]*user_events.Event, error) {
+		resp, err = tx.Admin().ChangePassword(ctx, pass)
+		if err != nil {
+			return nil, err
+		}
+		admin, err := tx.Admin().GetByLogin(ctx, pass.UserLogin)
+		if err != nil {
+			return nil, err
+		}
+		return []*user_events.Event{events.PasswordChanged("admin", admin.Id)}, nil
+	})
+	return resp, err
+}
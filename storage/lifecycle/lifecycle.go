@@ -0,0 +1,88 @@
+// Package lifecycle wraps a storage.StorageI so that every change to a user
+// or admin also writes the matching event to the outbox, in the same
+// transaction: one message for the event publishers and one per subscribed
+// webhook, plus one for the task service when a user is deleted, restored
+// or purged. The outbox dispatcher then delivers them, so other services
+// hear about exactly the changes that were committed.
+package lifecycle
+
+import (
+	"context"
+	"go_user_service/genproto/user_events"
+	"go_user_service/outbox"
+	"go_user_service/storage"
+)
+
+type Options struct {
+	// CascadeTasks carries user deletes, restores and purges over to the
+	// user's tasks
+	CascadeTasks bool
+}
+
+type Store struct {
+	storage.StorageI
+	opts Options
+}
+
+func New(strg storage.StorageI, opts Options) *Store {
+	return &Store{StorageI: strg, opts: opts}
+}
+
+func (s *Store) User() storage.UserRepoI {
+	return &userRepo{UserRepoI: s.StorageI.User(), s: s}
+}
+
+func (s *Store) Admin() storage.AdminRepoI {
+	return &adminRepo{AdminRepoI: s.StorageI.Admin(), s: s}
+}
+
+func (s *Store) WithTx(ctx context.Context, fn func(storage.StorageI) error) error {
+	return s.StorageI.WithTx(ctx, func(tx storage.StorageI) error {
+		return fn(&Store{StorageI: tx, opts: s.opts})
+	})
+}
+
+// record runs change in a transaction and adds the events it returns to the
+// outbox before committing.
+func (s *Store) record(ctx context.Context, change func(tx storage.StorageI) ([]*user_events.Event, error)) error {
+	return s.StorageI.WithTx(ctx, func(tx storage.StorageI) error {
+		evs, err := change(tx)
+		if err != nil || len(evs) == 0 {
+			return err
+		}
+
+		hooks, err := tx.Webhook().GetAll(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, ev := range evs {
+			msg, err := outbox.NewEvent(ev)
+			if err != nil {
+				return err
+			}
+			deliveries, err := outbox.NewWebhookDeliveries(hooks, ev)
+			if err != nil {
+				return err
+			}
+			msgs := append(deliveries, msg)
+
+			if s.opts.CascadeTasks {
+				cascade, err := outbox.NewTaskCascadeFor(ev)
+				if err != nil {
+					return err
+				}
+				if cascade != nil {
+					msgs = append(msgs, cascade)
+				}
+			}
+
+			for _, msg := range msgs {
+				if err = tx.Outbox().Add(ctx, msg); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,128 @@
+package lifecycle
+
+import (
+	"context"
+	"go_user_service/genproto/user_events"
+	"go_user_service/genproto/user_service"
+	"go_user_service/pkg/events"
+	"go_user_service/storage"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type userRepo struct {
+	storage.UserRepoI
+	s *Store
+}
+
+func userCreated(user *user_service.GetUser) *user_events.Event {
+	return events.UserCreated(&user_events.UserCreated{
+		UserId:    user.Id,
+		UserLogin: user.UserLogin,
+		Email:     user.Email,
+		Fullname:  user.Fullname,
+	})
+}
+
+func (c *userRepo) Create(ctx context.Context, req *user_service.CreateUser) (user *user_service.GetUser, err error) {
+	err = c.s.record(ctx, func(tx storage.StorageI) ([]*user_events.Event, error) {
+		user, err = tx.User().Create(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return []*user_events.Event{userCreated(user)}, nil
+	})
+	return user, err
+}
+
+func (c *userRepo) CreateMany(ctx context.Context, reqs []*user_service.CreateUser) (users []*user_service.GetUser, err error) {
+	err = c.s.record(ctx, func(tx storage.StorageI) ([]*user_events.Event, error) {
+		users, err = tx.User().CreateMany(ctx, reqs)
+		if err != nil {
+			return nil, err
+		}
+		evs := make([]*user_events.Event, len(users))
+		for i, user := range users {
+			evs[i] = userCreated(user)
+		}
+		return evs, nil
+	})
+	return users, err
+}
+
+func (c *userRepo) Update(ctx context.Context, req *user_service.UpdateUser) (user *user_service.GetUser, err error) {
+	err = c.s.record(ctx, func(tx storage.StorageI) ([]*user_events.Event, error) {
+		user, err = tx.User().Update(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return []*user_events.Event{events.UserUpdated(&user_events.UserUpdated{
+			UserId:    user.Id,
+			UserLogin: user.UserLogin,
+			Email:     user.Email,
+			Fullname:  user.Fullname,
+		})}, nil
+	})
+	return user, err
+}
+
+// Delete only records an event when an active user was actually deleted.
+func (c *userRepo) Delete(ctx context.Context, id *user_service.UserPrimaryKey) (resp emptypb.Empty, err error) {
+	err = c.s.record(ctx, func(tx storage.StorageI) ([]*user_events.Event, error) {
+		active, err := tx.User().Check(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = tx.User().Delete(ctx, id); err != nil {
+			return nil, err
+		}
+		if !active.Check {
+			return nil, nil
+		}
+		return []*user_events.Event{events.UserDeleted(id.Id)}, nil
+	})
+	return emptypb.Empty{}, err
+}
+
+// Restore only records an event when a deleted user was actually restored.
+func (c *userRepo) Restore(ctx context.Context, id *user_service.UserPrimaryKey) (resp emptypb.Empty, err error) {
+	err = c.s.record(ctx, func(tx storage.StorageI) ([]*user_events.Event, error) {
+		active, err := tx.User().Check(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = tx.User().Restore(ctx, id); err != nil {
+			return nil, err
+		}
+		if active.Check {
+			return nil, nil
+		}
+		return []*user_events.Event{events.UserRestored(id.Id)}, nil
+	})
+	return emptypb.Empty{}, err
+}
+
+func (c *userRepo) Purge(ctx context.Context, id *user_service.UserPrimaryKey) (resp emptypb.Empty, err error) {
+	err = c.s.record(ctx, func(tx storage.StorageI) ([]*user_events.Event, error) {
+		if _, err := tx.User().Purge(ctx, id); err != nil {
+			return nil, err
+		}
+		return []*user_events.Event{events.UserPurged(id.Id)}, nil
+	})
+	return emptypb.Empty{}, err
+}
+
+func (c *userRepo) ChangePassword(ctx context.Context, pass *user_service.UserChangePassword) (resp *user_service.UserChangePasswordResp, err error) {
+	err = c.s.record(ctx, func(tx storage.StorageI) ([]*user_events.Event, error) {
+		resp, err = tx.User().ChangePassword(ctx, pass)
+		if err != nil {
+			return nil, err
+		}
+		user, err := tx.User().GetByLogin(ctx, pass.UserLogin)
+		if err != nil {
+			return nil, err
+		}
+		return []*user_events.Event{events.PasswordChanged("user", user.Id)}, nil
+	})
+	return resp, err
+}
@@ -0,0 +1,232 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	adm "go_user_service/genproto/admin_service"
+	"go_user_service/pkg/hash"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type admin struct {
+	id        string
+	userLogin string
+	birthday  string
+	gender    string
+	fullname  string
+	email     string
+	phone     string
+	password  string
+	createdAt string
+	updatedAt string
+	deletedAt string
+	order     int
+}
+
+func (a *admin) get() *adm.GetAdmin {
+	return &adm.GetAdmin{
+		Id:        a.id,
+		UserLogin: a.userLogin,
+		Birthday:  a.birthday,
+		Gender:    a.gender,
+		Fullname:  a.fullname,
+		Email:     a.email,
+		Phone:     a.phone,
+		CreatedAt: a.createdAt,
+		UpdatedAt: a.updatedAt,
+	}
+}
+
+type adminRepo struct {
+	s *Store
+}
+
+func (d *data) activeAdmin(id string) (*admin, error) {
+	a, ok := d.admins[id]
+	if !ok || a.deletedAt != "" {
+		return nil, pgx.ErrNoRows
+	}
+	return a, nil
+}
+
+func (d *data) adminByLogin(login string) (*admin, error) {
+	for _, a := range d.admins {
+		if a.userLogin == login && a.deletedAt == "" {
+			return a, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (c *adminRepo) Create(ctx context.Context, req *adm.CreateAdmin) (*adm.GetAdmin, error) {
+	password, err := hash.HashPassword(ctx, req.UserPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *adm.GetAdmin
+	err = c.s.do(func(d *data) error {
+		d.adminSeq++
+		d.order++
+		a := &admin{
+			id:        uuid.NewString(),
+			userLogin: "A" + fmt.Sprintf("%05d", d.adminSeq),
+			birthday:  req.Birthday,
+			gender:    req.Gender,
+			fullname:  req.Fullname,
+			email:     req.Email,
+			phone:     req.Phone,
+			password:  password,
+			createdAt: timestamp(time.Now()),
+			order:     d.order,
+		}
+		d.admins[a.id] = a
+		resp = a.get()
+		return nil
+	})
+	return resp, err
+}
+
+func (c *adminRepo) Update(ctx context.Context, req *adm.UpdateAdmin) (*adm.GetAdmin, error) {
+	var resp *adm.GetAdmin
+	err := c.s.do(func(d *data) error {
+		a, ok := d.admins[req.Id]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		a.birthday = req.Birthday
+		a.gender = req.Gender
+		a.fullname = req.Fullname
+		a.email = req.Email
+		a.phone = req.Phone
+		a.updatedAt = timestamp(time.Now())
+
+		if a.deletedAt != "" {
+			return pgx.ErrNoRows
+		}
+		resp = a.get()
+		return nil
+	})
+	return resp, err
+}
+
+func (c *adminRepo) GetAll(ctx context.Context, req *adm.GetListAdminRequest) (*adm.GetListAdminResponse, error) {
+	resp := &adm.GetListAdminResponse{}
+	err := c.s.do(func(d *data) error {
+		var admins []*admin
+		for _, a := range d.admins {
+			if a.deletedAt != "" {
+				continue
+			}
+			if req.Search != "" && !strings.Contains(strings.ToLower(a.fullname), strings.ToLower(req.Search)) {
+				continue
+			}
+			admins = append(admins, a)
+		}
+		sort.Slice(admins, func(i, j int) bool { return admins[i].order < admins[j].order })
+
+		start, end, err := page(len(admins), req.Offset, req.Limit)
+		if err != nil {
+			return err
+		}
+		for _, a := range admins[start:end] {
+			resp.Admins = append(resp.Admins, a.get())
+		}
+		resp.Count = int64(len(admins))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *adminRepo) GetById(ctx context.Context, id *adm.AdminPrimaryKey) (*adm.GetAdmin, error) {
+	resp := &adm.GetAdmin{}
+	err := c.s.do(func(d *data) error {
+		a, err := d.activeAdmin(id.Id)
+		if err != nil {
+			return err
+		}
+		resp = a.get()
+		return nil
+	})
+	return resp, err
+}
+
+func (c *adminRepo) Delete(ctx context.Context, id *adm.AdminPrimaryKey) (emptypb.Empty, error) {
+	err := c.s.do(func(d *data) error {
+		if a, ok := d.admins[id.Id]; ok {
+			a.deletedAt = timestamp(time.Now())
+		}
+		return nil
+	})
+	return emptypb.Empty{}, err
+}
+
+func (c *adminRepo) ChangePassword(ctx context.Context, pass *adm.AdminChangePassword) (*adm.AdminChangePasswordResp, error) {
+	var resp *adm.AdminChangePasswordResp
+	err := c.s.do(func(d *data) error {
+		a, err := d.adminByLogin(pass.UserLogin)
+		if err != nil {
+			return errors.New("incorrect login")
+		}
+		if err = hash.CompareHashAndPassword(ctx, a.password, pass.OldPassword); err != nil {
+			return errors.New("password mismatch")
+		}
+		newHashedPassword, err := hash.HashPassword(ctx, pass.NewPassword)
+		if err != nil {
+			return err
+		}
+		a.password = newHashedPassword
+		a.updatedAt = timestamp(time.Now())
+
+		resp = &adm.AdminChangePasswordResp{Comment: "Password changed successfully"}
+		return nil
+	})
+	return resp, err
+}
+
+func (c *adminRepo) GetByLogin(ctx context.Context, login string) (*adm.GetAdminByLogin, error) {
+	resp := &adm.GetAdminByLogin{}
+	err := c.s.do(func(d *data) error {
+		a, err := d.adminByLogin(login)
+		if err != nil {
+			return err
+		}
+		resp = &adm.GetAdminByLogin{
+			Id:           a.id,
+			UserLogin:    a.userLogin,
+			UserPassword: a.password,
+			Birthday:     a.birthday,
+			Gender:       a.gender,
+			Fullname:     a.fullname,
+			Email:        a.email,
+			Phone:        a.phone,
+			CreatedAt:    a.createdAt,
+			UpdatedAt:    a.updatedAt,
+		}
+		return nil
+	})
+	return resp, err
+}
+
+func (c *adminRepo) GetPassword(ctx context.Context, login string) (string, error) {
+	var password string
+	err := c.s.do(func(d *data) error {
+		a, err := d.adminByLogin(login)
+		if err != nil {
+			return err
+		}
+		password = a.password
+		return nil
+	})
+	return password, err
+}
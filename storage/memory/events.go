@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"go_user_service/genproto/user_events"
+	"go_user_service/storage"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// eventStreamMaxLen matches the EVENT_STREAM_MAX_LEN default.
+const eventStreamMaxLen = 100000
+
+type streamEntry struct {
+	id   string
+	data []byte
+}
+
+// eventStream keeps events in a slice with redis style ids. Readers waiting
+// for new events block on changed, which is closed and replaced on append.
+type eventStream struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	maxLen  int
+	entries []streamEntry
+	// trimmed is the id of the newest entry dropped by maxLen
+	trimmed string
+	lastMs  uint64
+	lastSeq uint64
+	changed chan struct{}
+}
+
+func newEventStream(now func() time.Time) *eventStream {
+	return &eventStream{
+		now:     now,
+		maxLen:  eventStreamMaxLen,
+		changed: make(chan struct{}),
+	}
+}
+
+func (e *eventStream) Append(ctx context.Context, ev *user_events.Event) (string, error) {
+	data, err := proto.Marshal(ev)
+	if err != nil {
+		return "", err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ms := uint64(e.now().UnixMilli())
+	if ms > e.lastMs {
+		e.lastMs, e.lastSeq = ms, 0
+	} else {
+		e.lastSeq++
+	}
+	id := fmt.Sprintf("%d-%d", e.lastMs, e.lastSeq)
+
+	e.entries = append(e.entries, streamEntry{id: id, data: data})
+	if len(e.entries) > e.maxLen {
+		drop := len(e.entries) - e.maxLen
+		e.trimmed = e.entries[drop-1].id
+		e.entries = append([]streamEntry(nil), e.entries[drop:]...)
+	}
+	close(e.changed)
+	e.changed = make(chan struct{})
+
+	return id, nil
+}
+
+func (e *eventStream) LastID(ctx context.Context) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.entries) == 0 {
+		return "0-0", nil
+	}
+	return e.entries[len(e.entries)-1].id, nil
+}
+
+func (e *eventStream) Read(ctx context.Context, after string, count int, block time.Duration) ([]*storage.StreamEvent, error) {
+	if _, _, err := storage.ParseStreamID(after); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(block)
+	defer timer.Stop()
+
+	for {
+		e.mu.Lock()
+		if e.trimmed != "" && after != "0-0" && storage.CompareStreamIDs(e.trimmed, after) > 0 {
+			e.mu.Unlock()
+			return nil, storage.ErrResumeTokenExpired
+		}
+
+		var evs []*storage.StreamEvent
+		for _, entry := range e.entries {
+			if len(evs) == count {
+				break
+			}
+			if storage.CompareStreamIDs(entry.id, after) <= 0 {
+				continue
+			}
+			var ev user_events.Event
+			if err := proto.Unmarshal(entry.data, &ev); err != nil {
+				e.mu.Unlock()
+				return nil, err
+			}
+			evs = append(evs, &storage.StreamEvent{Id: entry.id, Event: &ev})
+		}
+		changed := e.changed
+		e.mu.Unlock()
+
+		if len(evs) > 0 {
+			return evs, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			return nil, nil
+		case <-changed:
+		}
+	}
+}
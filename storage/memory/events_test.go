@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"go_user_service/genproto/user_events"
+	"go_user_service/storage"
+	"testing"
+	"time"
+)
+
+func TestEventStreamTrim(t *testing.T) {
+	ctx := context.Background()
+	e := newEventStream(time.Now)
+	e.maxLen = 2
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := e.Append(ctx, &user_events.Event{Id: "event"})
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	// only the token's own entry was trimmed, nothing after it is missing
+	evs, err := e.Read(ctx, ids[0], 10, time.Millisecond)
+	if err != nil || len(evs) != 2 {
+		t.Fatalf("Read after the trimmed entry returned %d events, %v, want 2", len(evs), err)
+	}
+
+	if _, err = e.Read(ctx, "1-0", 10, time.Millisecond); !errors.Is(err, storage.ErrResumeTokenExpired) {
+		t.Fatalf("Read from before the trimmed entry returned %v, want ErrResumeTokenExpired", err)
+	}
+}
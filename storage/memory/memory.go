@@ -0,0 +1,145 @@
+// Package memory is an in-process implementation of storage.StorageI and
+// storage.IRedisStorage. It mirrors the behaviour of the postgres and redis
+// packages, including their error values, and is meant for tests and local
+// runs without external dependencies.
+package memory
+
+import (
+	"context"
+	"go_user_service/storage"
+	"sync"
+	"time"
+)
+
+type data struct {
+	users    map[string]*user
+	admins   map[string]*admin
+	outbox   map[string]*outboxMessage
+	webhooks map[string]*webhook
+	tasks    map[string]*task
+	// webhookDeliveries is append only, so clones share the entries
+	webhookDeliveries []*webhookDelivery
+	userSeq           int
+	adminSeq          int
+	taskSeq           int
+	// order keeps insertion order, which stands in for created_at ordering
+	order int
+}
+
+func newData() *data {
+	return &data{
+		users:    make(map[string]*user),
+		admins:   make(map[string]*admin),
+		outbox:   make(map[string]*outboxMessage),
+		webhooks: make(map[string]*webhook),
+		tasks:    make(map[string]*task),
+	}
+}
+
+func (d *data) clone() *data {
+	c := *d
+	c.users = make(map[string]*user, len(d.users))
+	for id, u := range d.users {
+		copied := *u
+		c.users[id] = &copied
+	}
+	c.admins = make(map[string]*admin, len(d.admins))
+	for id, a := range d.admins {
+		copied := *a
+		c.admins[id] = &copied
+	}
+	c.outbox = make(map[string]*outboxMessage, len(d.outbox))
+	for id, m := range d.outbox {
+		copied := *m
+		c.outbox[id] = &copied
+	}
+	c.webhooks = make(map[string]*webhook, len(d.webhooks))
+	for id, w := range d.webhooks {
+		copied := *w
+		c.webhooks[id] = &copied
+	}
+	c.tasks = make(map[string]*task, len(d.tasks))
+	for id, t := range d.tasks {
+		copied := *t
+		c.tasks[id] = &copied
+	}
+	c.webhookDeliveries = append([]*webhookDelivery(nil), d.webhookDeliveries...)
+	return &c
+}
+
+type state struct {
+	mu   sync.Mutex
+	data *data
+}
+
+type Store struct {
+	st    *state
+	inTx  bool
+	redis storage.IRedisStorage
+}
+
+func New(redis storage.IRedisStorage) storage.StorageI {
+	return &Store{
+		st:    &state{data: newData()},
+		redis: redis,
+	}
+}
+
+// do runs fn with exclusive access to the data. Inside WithTx the lock is
+// already held for the whole transaction.
+func (s *Store) do(fn func(*data) error) error {
+	if !s.inTx {
+		s.st.mu.Lock()
+		defer s.st.mu.Unlock()
+	}
+	return fn(s.st.data)
+}
+
+func (s *Store) CloseDB() {}
+
+func (s *Store) Ping(ctx context.Context) error { return nil }
+
+// WithTx runs fn while holding the store lock, so transactions are fully
+// serialized. Changes made by fn are rolled back if it returns an error.
+func (s *Store) WithTx(ctx context.Context, fn func(storage.StorageI) error) error {
+	if !s.inTx {
+		s.st.mu.Lock()
+		defer s.st.mu.Unlock()
+	}
+
+	snapshot := s.st.data.clone()
+	if err := fn(&Store{st: s.st, inTx: true, redis: s.redis}); err != nil {
+		s.st.data = snapshot
+		return err
+	}
+	return nil
+}
+
+func (s *Store) Admin() storage.AdminRepoI {
+	return &adminRepo{s: s}
+}
+
+func (s *Store) User() storage.UserRepoI {
+	return &userRepo{s: s}
+}
+
+func (s *Store) Outbox() storage.OutboxRepoI {
+	return &outboxRepo{s: s}
+}
+
+func (s *Store) Webhook() storage.WebhookRepoI {
+	return &webhookRepo{s: s}
+}
+
+func (s *Store) Task() storage.TaskRepoI {
+	return &taskRepo{s: s}
+}
+
+func (s *Store) Redis() storage.IRedisStorage {
+	return s.redis
+}
+
+// timestamp formats t the way a postgres TIMESTAMP column scans into a string.
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
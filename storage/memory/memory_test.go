@@ -0,0 +1,20 @@
+package memory
+
+import (
+	"go_user_service/config"
+	"go_user_service/storage"
+	"go_user_service/storage/storagetest"
+	"testing"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.RunStorage(t, func(t *testing.T) storage.StorageI {
+		return New(NewRedis(config.Load()))
+	})
+}
+
+func TestRedis(t *testing.T) {
+	storagetest.RunRedis(t, func(t *testing.T) storage.IRedisStorage {
+		return NewRedis(config.Load())
+	})
+}
@@ -0,0 +1,184 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"go_user_service/storage"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type outboxMessage struct {
+	storage.OutboxMessage
+	order int
+}
+
+func (m *outboxMessage) get() *storage.OutboxMessage {
+	msg := m.OutboxMessage
+	msg.Payload = append([]byte(nil), m.Payload...)
+	return &msg
+}
+
+type outboxRepo struct {
+	s *Store
+}
+
+func (c *outboxRepo) Add(ctx context.Context, msg *storage.OutboxMessage) error {
+	return c.s.do(func(d *data) error {
+		if msg.Id == "" {
+			msg.Id = uuid.NewString()
+		}
+		if _, ok := d.outbox[msg.Id]; ok {
+			return errors.New(`duplicate key value violates unique constraint "outbox_pkey"`)
+		}
+
+		now := time.Now()
+		d.order++
+		m := &outboxMessage{
+			OutboxMessage: storage.OutboxMessage{
+				Id:          msg.Id,
+				Kind:        msg.Kind,
+				Payload:     append([]byte(nil), msg.Payload...),
+				Status:      storage.OutboxPending,
+				AvailableAt: now,
+				CreatedAt:   now,
+			},
+			order: d.order,
+		}
+		d.outbox[m.Id] = m
+		*msg = *m.get()
+		return nil
+	})
+}
+
+func (c *outboxRepo) Claim(ctx context.Context, limit int, lease time.Duration) ([]*storage.OutboxMessage, error) {
+	var msgs []*storage.OutboxMessage
+	err := c.s.do(func(d *data) error {
+		now := time.Now()
+
+		var due []*outboxMessage
+		for _, m := range d.outbox {
+			if m.Status == storage.OutboxPending && !m.AvailableAt.After(now) {
+				due = append(due, m)
+			}
+		}
+		sort.Slice(due, func(i, j int) bool {
+			if !due[i].AvailableAt.Equal(due[j].AvailableAt) {
+				return due[i].AvailableAt.Before(due[j].AvailableAt)
+			}
+			return due[i].order < due[j].order
+		})
+		if len(due) > limit {
+			due = due[:limit]
+		}
+
+		for _, m := range due {
+			m.Attempts++
+			m.AvailableAt = now.Add(lease)
+			msgs = append(msgs, m.get())
+		}
+		return nil
+	})
+	return msgs, err
+}
+
+func (c *outboxRepo) MarkSent(ctx context.Context, id string) error {
+	return c.s.do(func(d *data) error {
+		if m, ok := d.outbox[id]; ok {
+			m.Status = storage.OutboxSent
+			m.Payload = []byte("{}")
+			m.LastError = ""
+			m.SentAt = time.Now()
+		}
+		return nil
+	})
+}
+
+func (c *outboxRepo) MarkFailed(ctx context.Context, id, lastError string, retryIn time.Duration, dead bool) error {
+	return c.s.do(func(d *data) error {
+		if m, ok := d.outbox[id]; ok {
+			m.Status = storage.OutboxPending
+			if dead {
+				m.Status = storage.OutboxDead
+			}
+			m.LastError = lastError
+			m.AvailableAt = time.Now().Add(retryIn)
+		}
+		return nil
+	})
+}
+
+func (c *outboxRepo) List(ctx context.Context, req *storage.OutboxListRequest) ([]*storage.OutboxMessage, int64, error) {
+	var (
+		msgs  []*storage.OutboxMessage
+		count int64
+	)
+	err := c.s.do(func(d *data) error {
+		var matched []*outboxMessage
+		for _, m := range d.outbox {
+			if (req.Status == "" || m.Status == req.Status) && (req.Kind == "" || m.Kind == req.Kind) {
+				matched = append(matched, m)
+			}
+		}
+		sort.Slice(matched, func(i, j int) bool { return matched[i].order > matched[j].order })
+
+		offset := req.Offset
+		if offset < 1 {
+			offset = 1
+		}
+		start, end, err := page(len(matched), offset, req.Limit)
+		if err != nil {
+			return err
+		}
+		for _, m := range matched[start:end] {
+			msgs = append(msgs, m.get())
+		}
+		count = int64(len(matched))
+		return nil
+	})
+	return msgs, count, err
+}
+
+func (c *outboxRepo) Retry(ctx context.Context, ids []string) (int64, error) {
+	var retried int64
+	err := c.s.do(func(d *data) error {
+		retry := func(m *outboxMessage) {
+			m.Status = storage.OutboxPending
+			m.Attempts = 0
+			m.AvailableAt = time.Now()
+			retried++
+		}
+
+		if len(ids) == 0 {
+			for _, m := range d.outbox {
+				if m.Status == storage.OutboxDead {
+					retry(m)
+				}
+			}
+			return nil
+		}
+		for _, id := range ids {
+			if m, ok := d.outbox[id]; ok && m.Status != storage.OutboxSent {
+				retry(m)
+			}
+		}
+		return nil
+	})
+	return retried, err
+}
+
+func (c *outboxRepo) PurgeSent(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := c.s.do(func(d *data) error {
+		for id, m := range d.outbox {
+			if m.Status == storage.OutboxSent && m.SentAt.Before(before) {
+				delete(d.outbox, id)
+				n++
+			}
+		}
+		return nil
+	})
+	return n, err
+}
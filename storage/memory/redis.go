@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"context"
+	"go_user_service/config"
+	"go_user_service/pkg/otp"
+	"go_user_service/storage"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type entry struct {
+	value     string
+	expiresAt time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+type otpEntry struct {
+	code      string
+	attempts  int
+	expiresAt time.Time
+}
+
+// Redis keeps keys in a map and expires them lazily on access. Missing keys
+// return redis.Nil like the real client.
+type Redis struct {
+	mu          sync.Mutex
+	now         func() time.Time
+	keys        map[string]entry
+	otps        map[string]*otpEntry
+	buckets     map[string]*bucket
+	maxAttempts int
+	otpLength   int
+	events      *eventStream
+}
+
+// NewRedis returns an in-memory redis. OTP codes are cfg.OTPLength digits
+// and wrong ones are accepted cfg.OTPMaxAttempts-1 times, as in the redis
+// package.
+func NewRedis(cfg config.Config) storage.IRedisStorage {
+	return &Redis{
+		now:         time.Now,
+		keys:        make(map[string]entry),
+		otps:        make(map[string]*otpEntry),
+		buckets:     make(map[string]*bucket),
+		maxAttempts: cfg.OTPMaxAttempts,
+		otpLength:   cfg.OTPLength,
+		events:      newEventStream(time.Now),
+	}
+}
+
+func (r *Redis) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (r *Redis) Close() error {
+	return nil
+}
+
+func (r *Redis) OTP() storage.OTPStoreI {
+	return r
+}
+
+func (r *Redis) Events() storage.EventStreamI {
+	return r.events
+}
+
+func (r *Redis) RateLimiter() storage.RateLimiterI {
+	return r
+}
+
+func (r *Redis) SetX(ctx context.Context, key string, value string, duration time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[key] = entry{value: value, expiresAt: r.now().Add(duration)}
+	return nil
+}
+
+func (r *Redis) SetNX(ctx context.Context, key string, value string, duration time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.keys[key]; ok && r.now().Before(e.expiresAt) {
+		return false, nil
+	}
+	r.keys[key] = entry{value: value, expiresAt: r.now().Add(duration)}
+	return true, nil
+}
+
+func (r *Redis) Get(ctx context.Context, key string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.keys[key]
+	if !ok {
+		return "", redis.Nil
+	}
+	if !r.now().Before(e.expiresAt) {
+		delete(r.keys, key)
+		return "", redis.Nil
+	}
+	return e.value, nil
+}
+
+func (r *Redis) Del(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.keys, key)
+	return nil
+}
+
+func otpKey(purpose, subject string) string {
+	return purpose + ":" + strings.ToLower(strings.TrimSpace(subject))
+}
+
+func (r *Redis) Issue(ctx context.Context, purpose, subject string, ttl time.Duration) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	code, err := otp.Numeric(r.otpLength)
+	if err != nil {
+		return "", err
+	}
+	r.otps[otpKey(purpose, subject)] = &otpEntry{code: code, expiresAt: r.now().Add(ttl)}
+	return code, nil
+}
+
+func (r *Redis) Verify(ctx context.Context, purpose, subject, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := otpKey(purpose, subject)
+	e, ok := r.otps[key]
+	if !ok || !r.now().Before(e.expiresAt) {
+		delete(r.otps, key)
+		return storage.ErrOTPNotFound
+	}
+
+	e.attempts++
+	if e.code == code {
+		delete(r.otps, key)
+		return nil
+	}
+	if e.attempts >= r.maxAttempts {
+		delete(r.otps, key)
+		return storage.ErrOTPAttemptsExceeded
+	}
+	return storage.ErrOTPMismatch
+}
+
+func (r *Redis) Take(ctx context.Context, key string, limit int, period time.Duration) (bool, time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	rate := float64(limit) / float64(period)
+
+	b, ok := r.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit), at: now}
+		r.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.at); elapsed > 0 {
+		b.tokens = math.Min(float64(limit), b.tokens+float64(elapsed)*rate)
+	}
+	b.at = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	return false, time.Duration(math.Ceil((1 - b.tokens) / rate)), nil
+}
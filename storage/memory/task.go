@@ -0,0 +1,242 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"go_user_service/genproto/task_service"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type task struct {
+	id             string
+	externalId     string
+	userId         string
+	title          string
+	status         string
+	description    string
+	deadline       string
+	createdAt      string
+	updatedAt      string
+	deletedAt      string
+	deletedByOwner bool
+	order          int
+}
+
+func (t *task) get() *task_service.GetTask {
+	return &task_service.GetTask{
+		Id:              t.id,
+		ExternalId:      t.externalId,
+		UserId:          t.userId,
+		Title:           t.title,
+		TaskStatus:      t.status,
+		TaskDescription: t.description,
+		Deadline:        t.deadline,
+		CreatedAt:       t.createdAt,
+		UpdatedAt:       t.updatedAt,
+	}
+}
+
+type taskRepo struct {
+	s *Store
+}
+
+// activeTask returns the task with id unless it doesn't exist or is deleted.
+func (d *data) activeTask(id string) (*task, error) {
+	t, ok := d.tasks[id]
+	if !ok || t.deletedAt != "" {
+		return nil, pgx.ErrNoRows
+	}
+	return t, nil
+}
+
+func (c *taskRepo) Create(ctx context.Context, req *task_service.CreateTask) (*task_service.GetTask, error) {
+	var resp *task_service.GetTask
+	err := c.s.do(func(d *data) error {
+		d.taskSeq++
+		d.order++
+		t := &task{
+			id:          uuid.NewString(),
+			externalId:  "T" + fmt.Sprintf("%05d", d.taskSeq),
+			userId:      req.UserId,
+			title:       req.Title,
+			status:      req.TaskStatus,
+			description: req.TaskDescription,
+			deadline:    req.Deadline,
+			createdAt:   timestamp(time.Now()),
+			order:       d.order,
+		}
+		d.tasks[t.id] = t
+		resp = t.get()
+		return nil
+	})
+	return resp, err
+}
+
+func (c *taskRepo) GetById(ctx context.Context, id *task_service.TaskPrimaryKey) (*task_service.GetTask, error) {
+	var resp *task_service.GetTask
+	err := c.s.do(func(d *data) error {
+		t, err := d.activeTask(id.Id)
+		if err != nil {
+			return err
+		}
+		resp = t.get()
+		return nil
+	})
+	return resp, err
+}
+
+func (c *taskRepo) GetByExternalId(ctx context.Context, id *task_service.TaskPrimaryKey) (*task_service.GetTask, error) {
+	var resp *task_service.GetTask
+	err := c.s.do(func(d *data) error {
+		for _, t := range d.tasks {
+			if t.externalId == id.Id && t.deletedAt == "" {
+				resp = t.get()
+				return nil
+			}
+		}
+		return pgx.ErrNoRows
+	})
+	return resp, err
+}
+
+func (c *taskRepo) Update(ctx context.Context, req *task_service.UpdateTask) (*task_service.GetTask, error) {
+	var resp *task_service.GetTask
+	err := c.s.do(func(d *data) error {
+		t, err := d.activeTask(req.Id)
+		if err != nil {
+			return err
+		}
+		t.userId = req.UserId
+		t.title = req.Title
+		t.deadline = req.Deadline
+		t.updatedAt = timestamp(time.Now())
+		resp = t.get()
+		return nil
+	})
+	return resp, err
+}
+
+func (c *taskRepo) ChangeStatus(ctx context.Context, req *task_service.TaskChangeStatus) (string, error) {
+	var old string
+	err := c.s.do(func(d *data) error {
+		t, err := d.activeTask(req.TaskId)
+		if err != nil {
+			return err
+		}
+		old = t.status
+		t.status = req.NewStatus
+		t.updatedAt = timestamp(time.Now())
+		return nil
+	})
+	return old, err
+}
+
+func (c *taskRepo) Delete(ctx context.Context, id *task_service.TaskPrimaryKey) (emptypb.Empty, error) {
+	err := c.s.do(func(d *data) error {
+		t, err := d.activeTask(id.Id)
+		if err != nil {
+			return err
+		}
+		t.deletedAt = timestamp(time.Now())
+		return nil
+	})
+	return emptypb.Empty{}, err
+}
+
+func (c *taskRepo) GetAll(ctx context.Context, req *task_service.GetListTaskRequest) (*task_service.GetListTaskResponse, error) {
+	resp := &task_service.GetListTaskResponse{}
+	err := c.s.do(func(d *data) error {
+		var tasks []*task
+		for _, t := range d.tasks {
+			switch {
+			case t.deletedAt != "",
+				req.OwnerId != "" && t.userId != req.OwnerId,
+				req.Search != "" && !strings.Contains(strings.ToLower(t.title), strings.ToLower(req.Search)),
+				req.FromDate != "" && t.createdAt < req.FromDate,
+				req.ToDate != "" && t.createdAt > req.ToDate:
+				continue
+			}
+			tasks = append(tasks, t)
+		}
+		sort.Slice(tasks, func(i, j int) bool { return tasks[i].order < tasks[j].order })
+
+		offset := req.Offset
+		if offset < 1 {
+			offset = 1
+		}
+		start, end, err := page(len(tasks), offset, req.Limit)
+		if err != nil {
+			return err
+		}
+		for _, t := range tasks[start:end] {
+			got := t.get()
+			resp.Tasks = append(resp.Tasks, &task_service.GetListTask{
+				Id:              got.Id,
+				ExternalId:      got.ExternalId,
+				UserId:          got.UserId,
+				Title:           got.Title,
+				TaskStatus:      got.TaskStatus,
+				TaskDescription: got.TaskDescription,
+				Deadline:        got.Deadline,
+				CreatedAt:       got.CreatedAt,
+				UpdatedAt:       got.UpdatedAt,
+			})
+		}
+		resp.Count = int64(len(tasks))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *taskRepo) DeleteByOwner(ctx context.Context, ownerID string) (int64, error) {
+	var n int64
+	err := c.s.do(func(d *data) error {
+		for _, t := range d.tasks {
+			if t.userId == ownerID && t.deletedAt == "" {
+				t.deletedAt = timestamp(time.Now())
+				t.deletedByOwner = true
+				n++
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+func (c *taskRepo) RestoreByOwner(ctx context.Context, ownerID string) (int64, error) {
+	var n int64
+	err := c.s.do(func(d *data) error {
+		for _, t := range d.tasks {
+			if t.userId == ownerID && t.deletedByOwner {
+				t.deletedAt = ""
+				t.deletedByOwner = false
+				n++
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+func (c *taskRepo) PurgeByOwner(ctx context.Context, ownerID string) (int64, error) {
+	var n int64
+	err := c.s.do(func(d *data) error {
+		for id, t := range d.tasks {
+			if t.userId == ownerID {
+				delete(d.tasks, id)
+				n++
+			}
+		}
+		return nil
+	})
+	return n, err
+}
@@ -0,0 +1,429 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_user_service/genproto/user_service"
+	"go_user_service/pkg/hash"
+	"go_user_service/storage"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type user struct {
+	id        string
+	userLogin string
+	birthday  string
+	gender    string
+	fullname  string
+	email     string
+	phone     string
+	password  string
+	createdAt string
+	updatedAt string
+	deletedAt string
+	order     int
+}
+
+func (u *user) get() *user_service.GetUser {
+	return &user_service.GetUser{
+		Id:        u.id,
+		UserLogin: u.userLogin,
+		Birthday:  u.birthday,
+		Gender:    u.gender,
+		Fullname:  u.fullname,
+		Email:     u.email,
+		Phone:     u.phone,
+		CreatedAt: u.createdAt,
+		UpdatedAt: u.updatedAt,
+	}
+}
+
+type userRepo struct {
+	s *Store
+}
+
+func (d *data) emailTaken(email string) bool {
+	for _, u := range d.users {
+		if u.email == email {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *data) insertUser(req *user_service.CreateUser, password string) *user {
+	d.userSeq++
+	d.order++
+	u := &user{
+		id:        uuid.NewString(),
+		userLogin: "S" + fmt.Sprintf("%05d", d.userSeq),
+		birthday:  req.Birthday,
+		gender:    req.Gender,
+		fullname:  req.Fullname,
+		email:     req.Email,
+		phone:     req.Phone,
+		password:  password,
+		createdAt: timestamp(time.Now()),
+		order:     d.order,
+	}
+	d.users[u.id] = u
+	return u
+}
+
+// activeUser returns the user with id unless it doesn't exist or is deleted.
+func (d *data) activeUser(id string) (*user, error) {
+	u, ok := d.users[id]
+	if !ok || u.deletedAt != "" {
+		return nil, pgx.ErrNoRows
+	}
+	return u, nil
+}
+
+func (d *data) userByLogin(login string) (*user, error) {
+	for _, u := range d.users {
+		if u.userLogin == login && u.deletedAt == "" {
+			return u, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+// filterUsers returns the active users matching search, oldest first.
+func (d *data) filterUsers(search string) []*user {
+	var users []*user
+	for _, u := range d.users {
+		if u.deletedAt != "" {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(u.fullname), strings.ToLower(search)) {
+			continue
+		}
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].order < users[j].order })
+	return users
+}
+
+// page applies the OFFSET (offset-1)*limit LIMIT limit paging used by GetAll.
+func page(n int, offset, limit int64) (int, int, error) {
+	start := (offset - 1) * limit
+	if start < 0 {
+		return 0, 0, errors.New("OFFSET must not be negative")
+	}
+	if limit < 0 {
+		return 0, 0, errors.New("LIMIT must not be negative")
+	}
+	if start > int64(n) {
+		start = int64(n)
+	}
+	end := start + limit
+	if end > int64(n) {
+		end = int64(n)
+	}
+	return int(start), int(end), nil
+}
+
+func (c *userRepo) Create(ctx context.Context, req *user_service.CreateUser) (*user_service.GetUser, error) {
+	password, err := hash.HashPassword(ctx, req.UserPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *user_service.GetUser
+	err = c.s.do(func(d *data) error {
+		if d.emailTaken(req.Email) {
+			return errors.New("duplicate key value violates unique constraint \"users_email_key\"")
+		}
+		resp = d.insertUser(req, password).get()
+		return nil
+	})
+	return resp, err
+}
+
+func (c *userRepo) Update(ctx context.Context, req *user_service.UpdateUser) (*user_service.GetUser, error) {
+	var resp *user_service.GetUser
+	err := c.s.do(func(d *data) error {
+		u, ok := d.users[req.Id]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		for _, other := range d.users {
+			if other.id != u.id && other.email == req.Email {
+				return errors.New("duplicate key value violates unique constraint \"users_email_key\"")
+			}
+		}
+		u.birthday = req.Birthday
+		u.gender = req.Gender
+		u.fullname = req.Fullname
+		u.email = req.Email
+		u.phone = req.Phone
+		u.updatedAt = timestamp(time.Now())
+
+		if u.deletedAt != "" {
+			return pgx.ErrNoRows
+		}
+		resp = u.get()
+		return nil
+	})
+	return resp, err
+}
+
+func (c *userRepo) GetAll(ctx context.Context, req *user_service.GetListUserRequest) (*user_service.GetListUserResponse, error) {
+	resp := &user_service.GetListUserResponse{}
+	err := c.s.do(func(d *data) error {
+		users := d.filterUsers(req.Search)
+		start, end, err := page(len(users), req.Offset, req.Limit)
+		if err != nil {
+			return err
+		}
+		for _, u := range users[start:end] {
+			resp.Users = append(resp.Users, u.get())
+		}
+		resp.Count = int64(len(users))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *userRepo) GetById(ctx context.Context, id *user_service.UserPrimaryKey) (*user_service.GetUser, error) {
+	resp := &user_service.GetUser{}
+	err := c.s.do(func(d *data) error {
+		u, err := d.activeUser(id.Id)
+		if err != nil {
+			return err
+		}
+		resp = u.get()
+		return nil
+	})
+	return resp, err
+}
+
+func (c *userRepo) Delete(ctx context.Context, id *user_service.UserPrimaryKey) (emptypb.Empty, error) {
+	err := c.s.do(func(d *data) error {
+		if u, ok := d.users[id.Id]; ok {
+			u.deletedAt = timestamp(time.Now())
+		}
+		return nil
+	})
+	return emptypb.Empty{}, err
+}
+
+func (c *userRepo) Restore(ctx context.Context, id *user_service.UserPrimaryKey) (emptypb.Empty, error) {
+	err := c.s.do(func(d *data) error {
+		u, ok := d.users[id.Id]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		u.deletedAt = ""
+		return nil
+	})
+	return emptypb.Empty{}, err
+}
+
+func (c *userRepo) Purge(ctx context.Context, id *user_service.UserPrimaryKey) (emptypb.Empty, error) {
+	err := c.s.do(func(d *data) error {
+		if _, ok := d.users[id.Id]; !ok {
+			return pgx.ErrNoRows
+		}
+		delete(d.users, id.Id)
+		return nil
+	})
+	return emptypb.Empty{}, err
+}
+
+func (c *userRepo) Check(ctx context.Context, id *user_service.UserPrimaryKey) (*user_service.CheckUserResp, error) {
+	resp := &user_service.CheckUserResp{}
+	err := c.s.do(func(d *data) error {
+		_, err := d.activeUser(id.Id)
+		resp.Check = err == nil
+		return nil
+	})
+	return resp, err
+}
+
+func (c *userRepo) ChangePassword(ctx context.Context, pass *user_service.UserChangePassword) (*user_service.UserChangePasswordResp, error) {
+	var resp *user_service.UserChangePasswordResp
+	err := c.s.do(func(d *data) error {
+		u, err := d.userByLogin(pass.UserLogin)
+		if err != nil {
+			return errors.New("incorrect login")
+		}
+		if err = hash.CompareHashAndPassword(ctx, u.password, pass.OldPassword); err != nil {
+			return errors.New("password mismatch")
+		}
+		newHashedPassword, err := hash.HashPassword(ctx, pass.NewPassword)
+		if err != nil {
+			return err
+		}
+		u.password = newHashedPassword
+		u.updatedAt = timestamp(time.Now())
+
+		resp = &user_service.UserChangePasswordResp{Comment: "Password changed successfully"}
+		return nil
+	})
+	return resp, err
+}
+
+func (c *userRepo) SetPassword(ctx context.Context, id, password string) error {
+	hashed, err := hash.HashPassword(ctx, password)
+	if err != nil {
+		return err
+	}
+	return c.s.do(func(d *data) error {
+		u, ok := d.users[id]
+		if !ok || u.deletedAt != "" || u.password != "" {
+			return pgx.ErrNoRows
+		}
+		u.password = hashed
+		u.updatedAt = timestamp(time.Now())
+		return nil
+	})
+}
+
+func (c *userRepo) GetByLogin(ctx context.Context, login string) (*user_service.GetUserByLogin, error) {
+	resp := &user_service.GetUserByLogin{}
+	err := c.s.do(func(d *data) error {
+		u, err := d.userByLogin(login)
+		if err != nil {
+			return err
+		}
+		resp = &user_service.GetUserByLogin{
+			Id:           u.id,
+			UserLogin:    u.userLogin,
+			UserPassword: u.password,
+			Birthday:     u.birthday,
+			Gender:       u.gender,
+			Fullname:     u.fullname,
+			Email:        u.email,
+			Phone:        u.phone,
+			CreatedAt:    u.createdAt,
+			UpdatedAt:    u.updatedAt,
+		}
+		return nil
+	})
+	return resp, err
+}
+
+func (c *userRepo) GetPassword(ctx context.Context, login string) (string, error) {
+	var password string
+	err := c.s.do(func(d *data) error {
+		u, err := d.userByLogin(login)
+		if err != nil {
+			return err
+		}
+		password = u.password
+		return nil
+	})
+	return password, err
+}
+
+func (c *userRepo) GetPersonalData(ctx context.Context, id *user_service.UserPrimaryKey) (*storage.PersonalData, error) {
+	var resp *storage.PersonalData
+	err := c.s.do(func(d *data) error {
+		u, ok := d.users[id.Id]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		resp = &storage.PersonalData{
+			Profile:   u.get(),
+			DeletedAt: u.deletedAt,
+		}
+		return nil
+	})
+	return resp, err
+}
+
+func (c *userRepo) CreateMany(ctx context.Context, reqs []*user_service.CreateUser) ([]*user_service.GetUser, error) {
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	passwords := make([]string, len(reqs))
+	for i, req := range reqs {
+		if req.UserPassword == "" {
+			continue
+		}
+		hashed, err := hash.HashPassword(ctx, req.UserPassword)
+		if err != nil {
+			return nil, err
+		}
+		passwords[i] = hashed
+	}
+
+	var users []*user_service.GetUser
+	err := c.s.WithTx(ctx, func(strg storage.StorageI) error {
+		return strg.(*Store).do(func(d *data) error {
+			for i, req := range reqs {
+				if d.emailTaken(req.Email) {
+					return errors.New("duplicate key value violates unique constraint \"users_email_key\"")
+				}
+				users = append(users, d.insertUser(req, passwords[i]).get())
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (c *userRepo) ExistingEmails(ctx context.Context, emails []string) ([]string, error) {
+	var existing []string
+	err := c.s.do(func(d *data) error {
+		wanted := make(map[string]bool, len(emails))
+		for _, email := range emails {
+			wanted[email] = true
+		}
+		for _, u := range d.users {
+			if wanted[strings.ToLower(u.email)] {
+				existing = append(existing, u.email)
+			}
+		}
+		return nil
+	})
+	return existing, err
+}
+
+func (c *userRepo) Stream(ctx context.Context, req *user_service.GetListUserRequest, fn func(*user_service.GetUser) error) error {
+	// copy the page first so fn runs without holding the lock
+	var users []*user_service.GetUser
+	err := c.s.do(func(d *data) error {
+		matched := d.filterUsers(req.Search)
+		start, end := 0, len(matched)
+		if req.Limit > 0 {
+			offset := req.Offset
+			if offset < 1 {
+				offset = 1
+			}
+			var err error
+			if start, end, err = page(len(matched), offset, req.Limit); err != nil {
+				return err
+			}
+		}
+		for _, u := range matched[start:end] {
+			users = append(users, u.get())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
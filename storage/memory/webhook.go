@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"go_user_service/storage"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+type webhook struct {
+	storage.Webhook
+	deleted bool
+	order   int
+}
+
+func (w *webhook) get() *storage.Webhook {
+	hook := w.Webhook
+	hook.Events = append([]string{}, w.Events...)
+	return &hook
+}
+
+type webhookDelivery struct {
+	storage.WebhookDelivery
+	order int
+}
+
+type webhookRepo struct {
+	s *Store
+}
+
+func (c *webhookRepo) Create(ctx context.Context, hook *storage.Webhook) error {
+	return c.s.do(func(d *data) error {
+		if hook.Id == "" {
+			hook.Id = uuid.NewString()
+		}
+		if _, ok := d.webhooks[hook.Id]; ok {
+			return errors.New(`duplicate key value violates unique constraint "webhooks_pkey"`)
+		}
+
+		d.order++
+		w := &webhook{Webhook: *hook, order: d.order}
+		w.Events = append([]string{}, hook.Events...)
+		w.CreatedAt = time.Now()
+		w.UpdatedAt = time.Time{}
+		d.webhooks[w.Id] = w
+		*hook = *w.get()
+		return nil
+	})
+}
+
+func (c *webhookRepo) Update(ctx context.Context, hook *storage.Webhook) error {
+	return c.s.do(func(d *data) error {
+		w, ok := d.webhooks[hook.Id]
+		if !ok || w.deleted {
+			return pgx.ErrNoRows
+		}
+
+		w.Url = hook.Url
+		w.Secret = hook.Secret
+		w.Events = append([]string{}, hook.Events...)
+		w.Active = hook.Active
+		w.UpdatedAt = time.Now()
+		*hook = *w.get()
+		return nil
+	})
+}
+
+func (c *webhookRepo) Delete(ctx context.Context, id string) error {
+	return c.s.do(func(d *data) error {
+		w, ok := d.webhooks[id]
+		if !ok || w.deleted {
+			return pgx.ErrNoRows
+		}
+		w.deleted = true
+		return nil
+	})
+}
+
+func (c *webhookRepo) GetById(ctx context.Context, id string) (*storage.Webhook, error) {
+	var hook *storage.Webhook
+	err := c.s.do(func(d *data) error {
+		w, ok := d.webhooks[id]
+		if !ok || w.deleted {
+			return pgx.ErrNoRows
+		}
+		hook = w.get()
+		return nil
+	})
+	return hook, err
+}
+
+func (c *webhookRepo) GetAll(ctx context.Context) ([]*storage.Webhook, error) {
+	var hooks []*storage.Webhook
+	err := c.s.do(func(d *data) error {
+		var active []*webhook
+		for _, w := range d.webhooks {
+			if !w.deleted {
+				active = append(active, w)
+			}
+		}
+		sort.Slice(active, func(i, j int) bool { return active[i].order < active[j].order })
+		for _, w := range active {
+			hooks = append(hooks, w.get())
+		}
+		return nil
+	})
+	return hooks, err
+}
+
+func (c *webhookRepo) AddDelivery(ctx context.Context, delivery *storage.WebhookDelivery) error {
+	return c.s.do(func(d *data) error {
+		if _, ok := d.webhooks[delivery.WebhookId]; !ok {
+			return errors.New(`insert or update on table "webhook_deliveries" violates foreign key constraint "webhook_deliveries_webhook_id_fkey"`)
+		}
+		if delivery.Id == "" {
+			delivery.Id = uuid.NewString()
+		}
+		delivery.CreatedAt = time.Now()
+
+		d.order++
+		d.webhookDeliveries = append(d.webhookDeliveries, &webhookDelivery{WebhookDelivery: *delivery, order: d.order})
+		return nil
+	})
+}
+
+func (c *webhookRepo) GetDeliveries(ctx context.Context, webhookID string, offset, limit int64) ([]*storage.WebhookDelivery, int64, error) {
+	var (
+		deliveries []*storage.WebhookDelivery
+		count      int64
+	)
+	if offset < 1 {
+		offset = 1
+	}
+	err := c.s.do(func(d *data) error {
+		var matched []*webhookDelivery
+		for _, delivery := range d.webhookDeliveries {
+			if delivery.WebhookId == webhookID {
+				matched = append(matched, delivery)
+			}
+		}
+		sort.Slice(matched, func(i, j int) bool { return matched[i].order > matched[j].order })
+
+		start, end, err := page(len(matched), offset, limit)
+		if err != nil {
+			return err
+		}
+		for _, delivery := range matched[start:end] {
+			copied := delivery.WebhookDelivery
+			deliveries = append(deliveries, &copied)
+		}
+		count = int64(len(matched))
+		return nil
+	})
+	return deliveries, count, err
+}
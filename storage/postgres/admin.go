@@ -10,26 +10,28 @@ import (
 	"go_user_service/pkg/hash"
 	"go_user_service/pkg/logger"
 	"go_user_service/storage"
-	"log"
 
-	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jackc/pgx/v4"
 )
 
 type adminRepo struct {
-	db *pgxpool.Pool
+	db        DB
+	txOptions TxOptions
+	log       logger.LoggerI
 }
 
-func NewAdminRepo(db *pgxpool.Pool) storage.AdminRepoI {
+func NewAdminRepo(db DB, txOptions TxOptions, log logger.LoggerI) storage.AdminRepoI {
 	return &adminRepo{
-		db: db,
+		db:        db,
+		txOptions: txOptions,
+		log:       log,
 	}
 }
 
-func generateAdminLogin(db *pgxpool.Pool, ctx context.Context) (string, error) {
+func generateAdminLogin(db DB, ctx context.Context) (string, error) {
 	var nextVal int
 	err := db.QueryRow(ctx, "SELECT nextval('admin_external_id_seq')").Scan(&nextVal)
 	if err != nil {
@@ -39,18 +41,28 @@ func generateAdminLogin(db *pgxpool.Pool, ctx context.Context) (string, error) {
 	return userLogin, nil
 }
 
-func (c *adminRepo) Create(ctx context.Context, req *adm.CreateAdmin) (*adm.GetAdmin, error) {
+func (c *adminRepo) Create(ctx context.Context, req *adm.CreateAdmin) (admin *adm.GetAdmin, err error) {
 	id := uuid.NewString()
-	pasword, err := hash.HashPassword(req.UserPassword)
+	pasword, err := hash.HashPassword(ctx, req.UserPassword)
 	if err != nil {
-		log.Println("error while hashing password", err)
+		logger.FromContext(ctx, c.log).Error("error while hashing password", logger.Error(err))
+		return nil, err
 	}
 
-	userLogin, err := generateAdminLogin(c.db, ctx)
+	err = inTx(ctx, c.db, c.txOptions, func(tx pgx.Tx) error {
+		admin, err = c.create(ctx, tx, id, pasword, req)
+		return err
+	})
+	return admin, err
+}
+
+func (c *adminRepo) create(ctx context.Context, tx pgx.Tx, id string, pasword string, req *adm.CreateAdmin) (*adm.GetAdmin, error) {
+	userLogin, err := generateAdminLogin(tx, ctx)
 	if err != nil {
-		log.Println("error while generating login", err)
+		logger.FromContext(ctx, c.log).Error("error while generating login", logger.Error(err))
+		return nil, err
 	}
-	comtag, err := c.db.Exec(ctx, `
+	_, err = tx.Exec(ctx, `
 		INSERT INTO admins (
 			id,
 			user_login,
@@ -71,20 +83,28 @@ func (c *adminRepo) Create(ctx context.Context, req *adm.CreateAdmin) (*adm.GetA
 		req.Phone,
 		pasword)
 	if err != nil {
-		log.Println("error while creating admin", comtag)
+		logger.FromContext(ctx, c.log).Error("error while creating admin", logger.Error(err))
 		return nil, err
 	}
 
-	admin, err := c.GetById(ctx, &adm.AdminPrimaryKey{Id: id})
+	admin, err := (&adminRepo{db: tx, log: c.log}).GetById(ctx, &adm.AdminPrimaryKey{Id: id})
 	if err != nil {
-		log.Println("error while getting admin by id")
+		logger.FromContext(ctx, c.log).Error("error while getting admin by id", logger.Error(err))
 		return nil, err
 	}
 	return admin, nil
 }
 
-func (c *adminRepo) Update(ctx context.Context, req *adm.UpdateAdmin) (*adm.GetAdmin, error) {
-	_, err := c.db.Exec(ctx, `
+func (c *adminRepo) Update(ctx context.Context, req *adm.UpdateAdmin) (admin *adm.GetAdmin, err error) {
+	err = inTx(ctx, c.db, c.txOptions, func(tx pgx.Tx) error {
+		admin, err = c.update(ctx, tx, req)
+		return err
+	})
+	return admin, err
+}
+
+func (c *adminRepo) update(ctx context.Context, tx pgx.Tx, req *adm.UpdateAdmin) (*adm.GetAdmin, error) {
+	_, err := tx.Exec(ctx, `
 		UPDATE admins SET
 		birthday = $1,
 		gender = $2,
@@ -101,19 +121,27 @@ func (c *adminRepo) Update(ctx context.Context, req *adm.UpdateAdmin) (*adm.GetA
 		req.Phone,
 		req.Id)
 	if err != nil {
-		log.Println("error while updating admin")
+		logger.FromContext(ctx, c.log).Error("error while updating admin", logger.Error(err))
 		return nil, err
 	}
 
-	admin, err := c.GetById(ctx, &adm.AdminPrimaryKey{Id: req.Id})
+	admin, err := (&adminRepo{db: tx, log: c.log}).GetById(ctx, &adm.AdminPrimaryKey{Id: req.Id})
 	if err != nil {
-		log.Println("error while getting admin by id")
+		logger.FromContext(ctx, c.log).Error("error while getting admin by id", logger.Error(err))
 		return nil, err
 	}
 	return admin, nil
 }
 
-func (c *adminRepo) GetAll(ctx context.Context, req *adm.GetListAdminRequest) (*adm.GetListAdminResponse, error) {
+func (c *adminRepo) GetAll(ctx context.Context, req *adm.GetListAdminRequest) (resp *adm.GetListAdminResponse, err error) {
+	err = inTx(ctx, c.db, c.txOptions, func(tx pgx.Tx) error {
+		resp, err = c.getAll(ctx, tx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *adminRepo) getAll(ctx context.Context, tx pgx.Tx, req *adm.GetListAdminRequest) (*adm.GetListAdminResponse, error) {
 	admins := adm.GetListAdminResponse{}
 	var (
 		created_at sql.NullString
@@ -139,10 +167,10 @@ func (c *adminRepo) GetAll(ctx context.Context, req *adm.GetListAdminRequest) (*
 			WHERE TRUE AND deleted_at is null ` + filter_by_name + `
 			OFFSET $1 LIMIT $2
 `
-	rows, err := c.db.Query(ctx, query, offest, req.Limit)
+	rows, err := tx.Query(ctx, query, offest, req.Limit)
 
 	if err != nil {
-		log.Println("error while getting all admins")
+		logger.FromContext(ctx, c.log).Error("error while getting all admins", logger.Error(err))
 		return nil, err
 	}
 	defer rows.Close()
@@ -170,7 +198,7 @@ func (c *adminRepo) GetAll(ctx context.Context, req *adm.GetListAdminRequest) (*
 		admins.Admins = append(admins.Admins, &admin)
 	}
 
-	err = c.db.QueryRow(ctx, `SELECT count(*) from admins WHERE TRUE AND deleted_at is null `+filter_by_name+``).Scan(&admins.Count)
+	err = tx.QueryRow(ctx, `SELECT count(*) from admins WHERE TRUE AND deleted_at is null `+filter_by_name+``).Scan(&admins.Count)
 	if err != nil {
 		return &admins, err
 	}
@@ -237,33 +265,42 @@ func (c *adminRepo) Delete(ctx context.Context, id *adm.AdminPrimaryKey) (emptyp
 
 ///////////////////////////////////////////
 
-func (c *adminRepo) ChangePassword(ctx context.Context, pass *adm.AdminChangePassword) (*adm.AdminChangePasswordResp, error) {
+func (c *adminRepo) ChangePassword(ctx context.Context, pass *adm.AdminChangePassword) (resp *adm.AdminChangePasswordResp, err error) {
+	err = inTx(ctx, c.db, c.txOptions, func(tx pgx.Tx) error {
+		resp, err = c.changePassword(ctx, tx, pass)
+		return err
+	})
+	return resp, err
+}
+
+func (c *adminRepo) changePassword(ctx context.Context, tx pgx.Tx, pass *adm.AdminChangePassword) (*adm.AdminChangePasswordResp, error) {
 	var hashedPass string
 	var resp adm.AdminChangePasswordResp
 	query := `SELECT user_password
 	FROM admins
-	WHERE user_login = $1 AND deleted_at is null`
+	WHERE user_login = $1 AND deleted_at is null
+	FOR UPDATE`
 
-	err := c.db.QueryRow(ctx, query,
+	err := tx.QueryRow(ctx, query,
 		pass.UserLogin,
 	).Scan(&hashedPass)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == pgx.ErrNoRows {
 			return nil, errors.New("incorrect login")
 		}
-		log.Println("failed to get admin password from database", logger.Error(err))
+		logger.FromContext(ctx, c.log).Error("failed to get admin password from database", logger.Error(err))
 		return nil, err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(hashedPass), []byte(pass.OldPassword))
+	err = hash.CompareHashAndPassword(ctx, hashedPass, pass.OldPassword)
 	if err != nil {
 		return nil, errors.New("password mismatch")
 	}
 
-	newHashedPassword, err := bcrypt.GenerateFromPassword([]byte(pass.NewPassword), bcrypt.DefaultCost)
+	newHashedPassword, err := hash.HashPassword(ctx, pass.NewPassword)
 	if err != nil {
-		log.Println("failed to generate admin new password", logger.Error(err))
+		logger.FromContext(ctx, c.log).Error("failed to generate admin new password", logger.Error(err))
 		return nil, err
 	}
 
@@ -272,9 +309,9 @@ func (c *adminRepo) ChangePassword(ctx context.Context, pass *adm.AdminChangePas
 		updated_at = NOW() 
 	WHERE user_login = $2 AND deleted_at is null`
 
-	_, err = c.db.Exec(ctx, query, newHashedPassword, pass.UserLogin)
+	_, err = tx.Exec(ctx, query, newHashedPassword, pass.UserLogin)
 	if err != nil {
-		log.Println("failed to change admin password in database", logger.Error(err))
+		logger.FromContext(ctx, c.log).Error("failed to change admin password in database", logger.Error(err))
 		return nil, err
 	}
 	resp.Comment = "Password changed successfully"
@@ -318,7 +355,7 @@ func (c *adminRepo) GetByLogin(ctx context.Context, login string) (*adm.GetAdmin
 	)
 
 	if err != nil {
-		log.Println("failed to scan admin by LOGIN from database", logger.Error(err))
+		logger.FromContext(ctx, c.log).Error("failed to scan admin by LOGIN from database", logger.Error(err))
 		return &adm.GetAdminByLogin{}, err
 	}
 
@@ -342,7 +379,7 @@ func (c *adminRepo) GetPassword(ctx context.Context, login string) (string, erro
 		if err == sql.ErrNoRows {
 			return "", errors.New("incorrect login")
 		} else {
-			log.Println("failed to get admin password from database", logger.Error(err))
+			logger.FromContext(ctx, c.log).Error("failed to get admin password from database", logger.Error(err))
 			return "", err
 		}
 	}
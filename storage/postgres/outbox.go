@@ -0,0 +1,226 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"go_user_service/pkg/logger"
+	"go_user_service/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+type outboxRepo struct {
+	db  DB
+	log logger.LoggerI
+}
+
+func NewOutboxRepo(db DB, log logger.LoggerI) storage.OutboxRepoI {
+	return &outboxRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+const outboxColumns = `
+	id,
+	kind,
+	payload,
+	status,
+	attempts,
+	COALESCE(last_error, ''),
+	available_at,
+	created_at,
+	sent_at`
+
+func scanOutbox(row pgx.Row) (*storage.OutboxMessage, error) {
+	var (
+		msg    storage.OutboxMessage
+		sentAt sql.NullTime
+	)
+	if err := row.Scan(
+		&msg.Id,
+		&msg.Kind,
+		&msg.Payload,
+		&msg.Status,
+		&msg.Attempts,
+		&msg.LastError,
+		&msg.AvailableAt,
+		&msg.CreatedAt,
+		&sentAt,
+	); err != nil {
+		return nil, err
+	}
+	if sentAt.Valid {
+		msg.SentAt = sentAt.Time
+	}
+	return &msg, nil
+}
+
+func (c *outboxRepo) Add(ctx context.Context, msg *storage.OutboxMessage) error {
+	if msg.Id == "" {
+		msg.Id = uuid.NewString()
+	}
+
+	row := c.db.QueryRow(ctx, `
+		INSERT INTO outbox (
+			id,
+			kind,
+			payload
+		) VALUES ($1, $2, $3)
+		RETURNING `+outboxColumns,
+		msg.Id,
+		msg.Kind,
+		msg.Payload,
+	)
+	stored, err := scanOutbox(row)
+	if err != nil {
+		logger.FromContext(ctx, c.log).Error("error while adding outbox message", logger.Error(err))
+		return err
+	}
+	*msg = *stored
+
+	return nil
+}
+
+func (c *outboxRepo) Claim(ctx context.Context, limit int, lease time.Duration) ([]*storage.OutboxMessage, error) {
+	rows, err := c.db.Query(ctx, `
+		UPDATE outbox SET
+			attempts = attempts + 1,
+			available_at = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE status = 'pending' AND available_at <= NOW()
+			ORDER BY available_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+outboxColumns,
+		limit,
+		lease.Milliseconds(),
+	)
+	if err != nil {
+		logger.FromContext(ctx, c.log).Error("error while claiming outbox messages", logger.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []*storage.OutboxMessage
+	for rows.Next() {
+		msg, err := scanOutbox(rows)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, rows.Err()
+}
+
+func (c *outboxRepo) MarkSent(ctx context.Context, id string) error {
+	_, err := c.db.Exec(ctx, `
+		UPDATE outbox SET
+			status = 'sent',
+			payload = '{}',
+			last_error = NULL,
+			sent_at = NOW()
+		WHERE id = $1`, id)
+	return err
+}
+
+func (c *outboxRepo) MarkFailed(ctx context.Context, id, lastError string, retryIn time.Duration, dead bool) error {
+	status := storage.OutboxPending
+	if dead {
+		status = storage.OutboxDead
+	}
+
+	_, err := c.db.Exec(ctx, `
+		UPDATE outbox SET
+			status = $2,
+			last_error = $3,
+			available_at = NOW() + $4 * INTERVAL '1 millisecond'
+		WHERE id = $1`, id, status, lastError, retryIn.Milliseconds())
+	return err
+}
+
+func (c *outboxRepo) List(ctx context.Context, req *storage.OutboxListRequest) ([]*storage.OutboxMessage, int64, error) {
+	filter := `WHERE ($1 = '' OR status = $1) AND ($2 = '' OR kind = $2)`
+	// offset 0 is what an unset proto field sends, it means the first page
+	offset := req.Offset
+	if offset < 1 {
+		offset = 1
+	}
+	offset = (offset - 1) * req.Limit
+
+	rows, err := c.db.Query(ctx, `
+		SELECT `+outboxColumns+`
+		FROM outbox
+		`+filter+`
+		ORDER BY created_at DESC, id
+		OFFSET $3 LIMIT $4`, req.Status, req.Kind, offset, req.Limit)
+	if err != nil {
+		logger.FromContext(ctx, c.log).Error("error while listing outbox messages", logger.Error(err))
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var msgs []*storage.OutboxMessage
+	for rows.Next() {
+		msg, err := scanOutbox(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		msgs = append(msgs, msg)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var count int64
+	err = c.db.QueryRow(ctx, `SELECT count(*) FROM outbox `+filter, req.Status, req.Kind).Scan(&count)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return msgs, count, nil
+}
+
+func (c *outboxRepo) Retry(ctx context.Context, ids []string) (int64, error) {
+	var (
+		tag pgconn.CommandTag
+		err error
+	)
+	if len(ids) == 0 {
+		tag, err = c.db.Exec(ctx, `
+			UPDATE outbox SET
+				status = 'pending',
+				attempts = 0,
+				available_at = NOW()
+			WHERE status = 'dead'`)
+	} else {
+		tag, err = c.db.Exec(ctx, `
+			UPDATE outbox SET
+				status = 'pending',
+				attempts = 0,
+				available_at = NOW()
+			WHERE status <> 'sent' AND id = ANY($1)`, ids)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (c *outboxRepo) PurgeSent(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := c.db.Exec(ctx, `
+		DELETE FROM outbox
+		WHERE status = 'sent' AND sent_at < $1`, before)
+	if err != nil {
+		logger.FromContext(ctx, c.log).Error("error while purging sent outbox messages", logger.Error(err))
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
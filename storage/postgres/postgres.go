@@ -4,23 +4,28 @@ import (
 	"context"
 	"fmt"
 	"go_user_service/config"
+	"go_user_service/pkg/logger"
 	"go_user_service/storage"
-	"go_user_service/storage/redis"
-	"log"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 type Store struct {
-	db            *pgxpool.Pool
+	pool          *pgxpool.Pool
+	db            DB
 	cfg           config.Config
+	log           logger.LoggerI
+	txOptions     TxOptions
 	administrator storage.AdminRepoI
 	user          storage.UserRepoI
+	outbox        storage.OutboxRepoI
+	webhook       storage.WebhookRepoI
+	task          storage.TaskRepoI
 	redis         storage.IRedisStorage
 }
 
-func NewPostgres(ctx context.Context, cfg config.Config, redis storage.IRedisStorage) (storage.StorageI, error) {
+func NewPostgres(ctx context.Context, cfg config.Config, log logger.LoggerI, redis storage.IRedisStorage) (storage.StorageI, error) {
 	config, err := pgxpool.ParseConfig(fmt.Sprintf(
 		"postgres://%s:%s@%s:%d/%s?sslmode=disable",
 		cfg.PostgresUser,
@@ -35,44 +40,99 @@ func NewPostgres(ctx context.Context, cfg config.Config, redis storage.IRedisSto
 
 	config.MaxConns = cfg.PostgresMaxConnections
 
+	if cfg.TracingExporter != "" && cfg.TracingExporter != "none" {
+		config.ConnConfig.Logger = queryTracer{withSQL: cfg.TracingDBStatement}
+		config.ConnConfig.LogLevel = pgx.LogLevelInfo
+	}
+
+	isoLevel, err := ParseIsoLevel(cfg.PostgresTxIsolation)
+	if err != nil {
+		return nil, err
+	}
+
 	pool, err := pgxpool.ConnectConfig(ctx, config)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Store{
-		db:    pool,
+		pool: pool,
+		db:   pool,
+		cfg:  cfg,
+		log:  log,
+		txOptions: TxOptions{
+			TxOptions:  pgx.TxOptions{IsoLevel: isoLevel},
+			MaxRetries: cfg.PostgresTxMaxRetries,
+		},
 		redis: redis,
 	}, err
 }
 
 func (s *Store) CloseDB() {
-	s.db.Close()
+	s.pool.Close()
 }
 
-func (l *Store) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
-	args := make([]interface{}, 0, len(data)+2) // making space for arguments + level + msg
-	args = append(args, level, msg)
-	for k, v := range data {
-		args = append(args, fmt.Sprintf("%s=%v", k, v))
-	}
-	log.Println(args...)
+func (s *Store) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+// Stat reports the pool statistics exported on /metrics.
+func (s *Store) Stat() *pgxpool.Stat {
+	return s.pool.Stat()
+}
+
+// WithTx runs fn against a store whose repos all share one transaction.
+// The transaction is committed when fn returns nil and re-run from the
+// start on serialization failures, so fn must not have side effects
+// outside the database. Nested calls use savepoints.
+func (s *Store) WithTx(ctx context.Context, fn func(storage.StorageI) error) error {
+	return inTx(ctx, s.db, s.txOptions, func(tx pgx.Tx) error {
+		return fn(&Store{
+			pool:      s.pool,
+			db:        tx,
+			cfg:       s.cfg,
+			log:       s.log,
+			txOptions: s.txOptions,
+			redis:     s.redis,
+		})
+	})
 }
 
 func (s *Store) Admin() storage.AdminRepoI {
 	if s.administrator == nil {
-		s.administrator = NewAdminRepo(s.db)
+		s.administrator = NewAdminRepo(s.db, s.txOptions, s.log)
 	}
 	return s.administrator
 }
 
 func (s *Store) User() storage.UserRepoI {
 	if s.user == nil {
-		s.user = NewUserRepo(s.db)
+		s.user = NewUserRepo(s.db, s.txOptions, s.log)
 	}
 	return s.user
 }
 
-func (s Store) Redis() storage.IRedisStorage {
-	return redis.New(s.cfg)
+func (s *Store) Outbox() storage.OutboxRepoI {
+	if s.outbox == nil {
+		s.outbox = NewOutboxRepo(s.db, s.log)
+	}
+	return s.outbox
+}
+
+func (s *Store) Webhook() storage.WebhookRepoI {
+	if s.webhook == nil {
+		s.webhook = NewWebhookRepo(s.db, s.log)
+	}
+	return s.webhook
+}
+
+func (s *Store) Task() storage.TaskRepoI {
+	if s.task == nil {
+		s.task = NewTaskRepo(s.db, s.txOptions, s.log)
+	}
+	return s.task
+}
+
+func (s *Store) Redis() storage.IRedisStorage {
+	return s.redis
 }
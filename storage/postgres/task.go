@@ -0,0 +1,289 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"go_user_service/genproto/task_service"
+	"go_user_service/pkg"
+	"go_user_service/pkg/logger"
+	"go_user_service/storage"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type taskRepo struct {
+	db        DB
+	txOptions TxOptions
+	log       logger.LoggerI
+}
+
+func NewTaskRepo(db DB, txOptions TxOptions, log logger.LoggerI) storage.TaskRepoI {
+	return &taskRepo{
+		db:        db,
+		txOptions: txOptions,
+		log:       log,
+	}
+}
+
+const taskColumns = `
+	id,
+	external_id,
+	user_id,
+	title,
+	task_status,
+	task_description,
+	deadline,
+	created_at,
+	updated_at`
+
+func scanTask(row pgx.Row) (*task_service.GetTask, error) {
+	var (
+		task        task_service.GetTask
+		description sql.NullString
+		deadline    sql.NullString
+		created_at  sql.NullString
+		updated_at  sql.NullString
+	)
+	if err := row.Scan(
+		&task.Id,
+		&task.ExternalId,
+		&task.UserId,
+		&task.Title,
+		&task.TaskStatus,
+		&description,
+		&deadline,
+		&created_at,
+		&updated_at,
+	); err != nil {
+		return nil, err
+	}
+	task.TaskDescription = pkg.NullStringToString(description)
+	task.Deadline = pkg.NullStringToString(deadline)
+	task.CreatedAt = pkg.NullStringToString(created_at)
+	task.UpdatedAt = pkg.NullStringToString(updated_at)
+	return &task, nil
+}
+
+func generateTaskExternalId(db DB, ctx context.Context) (string, error) {
+	var nextVal int
+	err := db.QueryRow(ctx, "SELECT nextval('task_external_id_seq')").Scan(&nextVal)
+	if err != nil {
+		return "", err
+	}
+	return "T" + fmt.Sprintf("%05d", nextVal), nil
+}
+
+func (c *taskRepo) Create(ctx context.Context, req *task_service.CreateTask) (task *task_service.GetTask, err error) {
+	err = inTx(ctx, c.db, c.txOptions, func(tx pgx.Tx) error {
+		externalId, err := generateTaskExternalId(tx, ctx)
+		if err != nil {
+			logger.FromContext(ctx, c.log).Error("error while generating task external id", logger.Error(err))
+			return err
+		}
+
+		task, err = scanTask(tx.QueryRow(ctx, `
+			INSERT INTO tasks (
+				id,
+				external_id,
+				user_id,
+				title,
+				task_status,
+				task_description,
+				deadline
+			) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING `+taskColumns,
+			uuid.NewString(),
+			externalId,
+			req.UserId,
+			req.Title,
+			req.TaskStatus,
+			sql.NullString{String: req.TaskDescription, Valid: req.TaskDescription != ""},
+			sql.NullString{String: req.Deadline, Valid: req.Deadline != ""},
+		))
+		if err != nil {
+			logger.FromContext(ctx, c.log).Error("error while creating task", logger.Error(err))
+		}
+		return err
+	})
+	return task, err
+}
+
+func (c *taskRepo) GetById(ctx context.Context, id *task_service.TaskPrimaryKey) (*task_service.GetTask, error) {
+	return scanTask(c.db.QueryRow(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE id = $1 AND deleted_at IS NULL`, id.Id))
+}
+
+func (c *taskRepo) GetByExternalId(ctx context.Context, id *task_service.TaskPrimaryKey) (*task_service.GetTask, error) {
+	return scanTask(c.db.QueryRow(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE external_id = $1 AND deleted_at IS NULL`, id.Id))
+}
+
+// Update changes the owner, title and deadline. The description is left as
+// it is, UpdateTask carries it as a number.
+func (c *taskRepo) Update(ctx context.Context, req *task_service.UpdateTask) (*task_service.GetTask, error) {
+	task, err := scanTask(c.db.QueryRow(ctx, `
+		UPDATE tasks SET
+			user_id = $2,
+			title = $3,
+			deadline = $4,
+			updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING `+taskColumns,
+		req.Id,
+		req.UserId,
+		req.Title,
+		sql.NullString{String: req.Deadline, Valid: req.Deadline != ""},
+	))
+	if err != nil && err != pgx.ErrNoRows {
+		logger.FromContext(ctx, c.log).Error("error while updating task", logger.Error(err))
+	}
+	return task, err
+}
+
+func (c *taskRepo) ChangeStatus(ctx context.Context, req *task_service.TaskChangeStatus) (old string, err error) {
+	err = inTx(ctx, c.db, c.txOptions, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			SELECT task_status
+			FROM tasks
+			WHERE id = $1 AND deleted_at IS NULL
+			FOR UPDATE`, req.TaskId).Scan(&old)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE tasks SET
+			task_status = $2,
+			updated_at = NOW()
+			WHERE id = $1`, req.TaskId, req.NewStatus)
+		return err
+	})
+	return old, err
+}
+
+func (c *taskRepo) Delete(ctx context.Context, id *task_service.TaskPrimaryKey) (emptypb.Empty, error) {
+	tag, err := c.db.Exec(ctx, `
+		UPDATE tasks SET
+		deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL`, id.Id)
+	if err != nil {
+		return emptypb.Empty{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return emptypb.Empty{}, pgx.ErrNoRows
+	}
+	return emptypb.Empty{}, nil
+}
+
+// GetAll lists active tasks, oldest first. from_date and to_date bound
+// created_at, search matches the title.
+func (c *taskRepo) GetAll(ctx context.Context, req *task_service.GetListTaskRequest) (resp *task_service.GetListTaskResponse, err error) {
+	var (
+		where = []string{"deleted_at IS NULL"}
+		args  []interface{}
+	)
+	filter := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if req.OwnerId != "" {
+		filter("user_id = $%d", req.OwnerId)
+	}
+	if req.Search != "" {
+		filter("title ILIKE '%%' || $%d::text || '%%'", req.Search)
+	}
+	if req.FromDate != "" {
+		filter("created_at >= $%d", req.FromDate)
+	}
+	if req.ToDate != "" {
+		filter("created_at <= $%d", req.ToDate)
+	}
+	cond := strings.Join(where, " AND ")
+
+	// offset 0 is what an unset proto field sends, it means the first page
+	offset := req.Offset
+	if offset < 1 {
+		offset = 1
+	}
+
+	err = inTx(ctx, c.db, c.txOptions, func(tx pgx.Tx) error {
+		resp = &task_service.GetListTaskResponse{}
+
+		rows, err := tx.Query(ctx, `
+			SELECT `+taskColumns+`
+			FROM tasks
+			WHERE `+cond+`
+			ORDER BY created_at, external_id
+			OFFSET $`+fmt.Sprint(len(args)+1)+` LIMIT $`+fmt.Sprint(len(args)+2),
+			append(args, (offset-1)*req.Limit, req.Limit)...)
+		if err != nil {
+			logger.FromContext(ctx, c.log).Error("error while getting all tasks", logger.Error(err))
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			task, err := scanTask(rows)
+			if err != nil {
+				return err
+			}
+			resp.Tasks = append(resp.Tasks, &task_service.GetListTask{
+				Id:              task.Id,
+				ExternalId:      task.ExternalId,
+				UserId:          task.UserId,
+				Title:           task.Title,
+				TaskStatus:      task.TaskStatus,
+				TaskDescription: task.TaskDescription,
+				Deadline:        task.Deadline,
+				CreatedAt:       task.CreatedAt,
+				UpdatedAt:       task.UpdatedAt,
+			})
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		return tx.QueryRow(ctx, `SELECT count(*) FROM tasks WHERE `+cond, args...).Scan(&resp.Count)
+	})
+	return resp, err
+}
+
+func (c *taskRepo) DeleteByOwner(ctx context.Context, ownerID string) (int64, error) {
+	tag, err := c.db.Exec(ctx, `
+		UPDATE tasks SET
+		deleted_at = NOW(),
+		deleted_by_owner = TRUE
+		WHERE user_id = $1 AND deleted_at IS NULL`, ownerID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (c *taskRepo) RestoreByOwner(ctx context.Context, ownerID string) (int64, error) {
+	tag, err := c.db.Exec(ctx, `
+		UPDATE tasks SET
+		deleted_at = NULL,
+		deleted_by_owner = FALSE
+		WHERE user_id = $1 AND deleted_by_owner`, ownerID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (c *taskRepo) PurgeByOwner(ctx context.Context, ownerID string) (int64, error) {
+	tag, err := c.db.Exec(ctx, `DELETE FROM tasks WHERE user_id = $1`, ownerID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
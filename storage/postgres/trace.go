@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"go_user_service/pkg/tracing"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryTracer turns the log entries pgx v4 writes after every query into
+// spans. pgx has no tracing hooks of its own, but each entry carries the
+// query context and duration, which is enough to place the span. Spans
+// carry the operation, e.g. SELECT. The statement text is only recorded
+// with withSQL, since some queries have user input such as search text
+// written into them. Arguments are never recorded.
+type queryTracer struct {
+	withSQL bool
+}
+
+func (t queryTracer) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	elapsed, ok := data["time"].(time.Duration)
+	if !ok {
+		return
+	}
+
+	name := msg
+	attrs := []attribute.KeyValue{semconv.DBSystemPostgreSQL}
+	if sql, ok := data["sql"].(string); ok {
+		name = operation(sql)
+		attrs = append(attrs, semconv.DBOperationName(name))
+		if t.withSQL {
+			attrs = append(attrs, semconv.DBQueryText(sql))
+		}
+	}
+	if table, ok := data["tableName"].(pgx.Identifier); ok {
+		name = "COPY " + table.Sanitize()
+	}
+	if rows, ok := data["rowCount"].(int); ok {
+		attrs = append(attrs, attribute.Int("db.row_count", rows))
+	}
+
+	end := time.Now()
+	_, span := tracing.Start(ctx, "postgres "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(end.Add(-elapsed)),
+		trace.WithAttributes(attrs...),
+	)
+	if err, ok := data["err"].(error); ok {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
+
+// operation is the first keyword of sql, e.g. SELECT.
+func operation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// DB is the subset of pgx used by the repos. Both *pgxpool.Pool and pgx.Tx
+// satisfy it, which lets the same repo run on the pool or inside WithTx.
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// TxOptions controls the transactions started by the store and its repos.
+type TxOptions struct {
+	pgx.TxOptions
+	// MaxRetries is how many times a transaction is re-run after a
+	// serialization failure or deadlock.
+	MaxRetries int
+}
+
+// ParseIsoLevel maps the POSTGRES_TX_ISOLATION values to pgx levels.
+func ParseIsoLevel(level string) (pgx.TxIsoLevel, error) {
+	switch strings.ToLower(strings.ReplaceAll(level, "_", " ")) {
+	case "", "read committed":
+		return pgx.ReadCommitted, nil
+	case "repeatable read":
+		return pgx.RepeatableRead, nil
+	case "serializable":
+		return pgx.Serializable, nil
+	default:
+		return "", errors.New("unknown transaction isolation level " + level)
+	}
+}
+
+type txBeginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// inTx runs fn in a transaction. On the pool a new transaction is started
+// with opts and retried on serialization failures; inside an existing
+// transaction a savepoint is used and retrying is left to the outermost call.
+func inTx(ctx context.Context, db DB, opts TxOptions, fn func(pgx.Tx) error) error {
+	pool, ok := db.(txBeginner)
+	if !ok {
+		return runTx(ctx, db.Begin, fn)
+	}
+
+	begin := func(ctx context.Context) (pgx.Tx, error) {
+		return pool.BeginTx(ctx, opts.TxOptions)
+	}
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, begin, fn)
+		if err == nil || attempt >= opts.MaxRetries || !isRetryable(err) {
+			return err
+		}
+
+		backoff := time.Duration(10*(attempt+1))*time.Millisecond + time.Duration(rand.Intn(10))*time.Millisecond
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func runTx(ctx context.Context, begin func(context.Context) (pgx.Tx, error), fn func(pgx.Tx) error) error {
+	tx, err := begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// isRetryable reports whether err is a serialization failure or a deadlock,
+// after which the whole transaction can safely be run again.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
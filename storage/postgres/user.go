@@ -10,26 +10,29 @@ import (
 	"go_user_service/pkg/hash"
 	"go_user_service/pkg/logger"
 	"go_user_service/storage"
-	"log"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jackc/pgx/v4"
 )
 
 type userRepo struct {
-	db *pgxpool.Pool
+	db        DB
+	txOptions TxOptions
+	log       logger.LoggerI
 }
 
-func NewUserRepo(db *pgxpool.Pool) storage.UserRepoI {
+func NewUserRepo(db DB, txOptions TxOptions, log logger.LoggerI) storage.UserRepoI {
 	return &userRepo{
-		db: db,
+		db:        db,
+		txOptions: txOptions,
+		log:       log,
 	}
 }
 
-func generateUserLogin(db *pgxpool.Pool, ctx context.Context) (string, error) {
+func generateUserLogin(db DB, ctx context.Context) (string, error) {
 	var nextVal int
 	err := db.QueryRow(ctx, "SELECT nextval('user_external_id_seq')").Scan(&nextVal)
 	if err != nil {
@@ -39,20 +42,30 @@ func generateUserLogin(db *pgxpool.Pool, ctx context.Context) (string, error) {
 	return userLogin, nil
 }
 
-func (c *userRepo) Create(ctx context.Context, req *user_service.CreateUser) (*user_service.GetUser, error) {
-	var birthday sql.NullString
+func (c *userRepo) Create(ctx context.Context, req *user_service.CreateUser) (user *user_service.GetUser, err error) {
+	birthday := sql.NullString{String: req.Birthday, Valid: req.Birthday != ""}
 	id := uuid.NewString()
-	pasword, err := hash.HashPassword(req.UserPassword)
+	pasword, err := hash.HashPassword(ctx, req.UserPassword)
 	if err != nil {
-		log.Println("error while hashing password", err)
+		logger.FromContext(ctx, c.log).Error("error while hashing password", logger.Error(err))
+		return nil, err
 	}
 
-	userLogin, err := generateUserLogin(c.db, ctx)
+	err = inTx(ctx, c.db, c.txOptions, func(tx pgx.Tx) error {
+		user, err = c.create(ctx, tx, id, birthday, pasword, req)
+		return err
+	})
+	return user, err
+}
+
+func (c *userRepo) create(ctx context.Context, tx pgx.Tx, id string, birthday sql.NullString, pasword string, req *user_service.CreateUser) (*user_service.GetUser, error) {
+	userLogin, err := generateUserLogin(tx, ctx)
 	if err != nil {
-		log.Println("error while generating login", err)
+		logger.FromContext(ctx, c.log).Error("error while generating login", logger.Error(err))
+		return nil, err
 	}
 
-	comtag, err := c.db.Exec(ctx, `
+	_, err = tx.Exec(ctx, `
 		INSERT INTO users (
 			id,
 			user_login,
@@ -73,22 +86,29 @@ func (c *userRepo) Create(ctx context.Context, req *user_service.CreateUser) (*u
 		req.Phone,
 		pasword)
 	if err != nil {
-		log.Println("error while creating user", comtag)
+		logger.FromContext(ctx, c.log).Error("error while creating user", logger.Error(err))
 		return nil, err
 	}
 	req.Birthday = pkg.NullStringToString(birthday)
 
-	user, err := c.GetById(ctx, &user_service.UserPrimaryKey{Id: id})
+	user, err := (&userRepo{db: tx, log: c.log}).GetById(ctx, &user_service.UserPrimaryKey{Id: id})
 	if err != nil {
-		log.Println("error while getting user by id")
+		logger.FromContext(ctx, c.log).Error("error while getting user by id", logger.Error(err))
 		return nil, err
 	}
 	return user, nil
 }
 
-func (c *userRepo) Update(ctx context.Context, req *user_service.UpdateUser) (*user_service.GetUser, error) {
+func (c *userRepo) Update(ctx context.Context, req *user_service.UpdateUser) (user *user_service.GetUser, err error) {
+	err = inTx(ctx, c.db, c.txOptions, func(tx pgx.Tx) error {
+		user, err = c.update(ctx, tx, req)
+		return err
+	})
+	return user, err
+}
 
-	_, err := c.db.Exec(ctx, `
+func (c *userRepo) update(ctx context.Context, tx pgx.Tx, req *user_service.UpdateUser) (*user_service.GetUser, error) {
+	_, err := tx.Exec(ctx, `
 		UPDATE users SET
 		birthday = $1,
 		gender = $2,
@@ -105,19 +125,27 @@ func (c *userRepo) Update(ctx context.Context, req *user_service.UpdateUser) (*u
 		req.Phone,
 		req.Id)
 	if err != nil {
-		log.Println("error while updating user")
+		logger.FromContext(ctx, c.log).Error("error while updating user", logger.Error(err))
 		return nil, err
 	}
 
-	user, err := c.GetById(ctx, &user_service.UserPrimaryKey{Id: req.Id})
+	user, err := (&userRepo{db: tx, log: c.log}).GetById(ctx, &user_service.UserPrimaryKey{Id: req.Id})
 	if err != nil {
-		log.Println("error while getting user by id")
+		logger.FromContext(ctx, c.log).Error("error while getting user by id", logger.Error(err))
 		return nil, err
 	}
 	return user, nil
 }
 
-func (c *userRepo) GetAll(ctx context.Context, req *user_service.GetListUserRequest) (*user_service.GetListUserResponse, error) {
+func (c *userRepo) GetAll(ctx context.Context, req *user_service.GetListUserRequest) (resp *user_service.GetListUserResponse, err error) {
+	err = inTx(ctx, c.db, c.txOptions, func(tx pgx.Tx) error {
+		resp, err = c.getAll(ctx, tx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (c *userRepo) getAll(ctx context.Context, tx pgx.Tx, req *user_service.GetListUserRequest) (*user_service.GetListUserResponse, error) {
 	users := user_service.GetListUserResponse{}
 
 	var (
@@ -144,10 +172,10 @@ func (c *userRepo) GetAll(ctx context.Context, req *user_service.GetListUserRequ
 			WHERE TRUE AND deleted_at is null ` + filter_by_name + `
 			OFFSET $1 LIMIT $2
 `
-	rows, err := c.db.Query(ctx, query, offest, req.Limit)
+	rows, err := tx.Query(ctx, query, offest, req.Limit)
 
 	if err != nil {
-		log.Println("error while getting all users")
+		logger.FromContext(ctx, c.log).Error("error while getting all users", logger.Error(err))
 		return nil, err
 	}
 	defer rows.Close()
@@ -175,7 +203,7 @@ func (c *userRepo) GetAll(ctx context.Context, req *user_service.GetListUserRequ
 		users.Users = append(users.Users, &user)
 	}
 
-	err = c.db.QueryRow(ctx, `SELECT count(*) from users WHERE TRUE AND deleted_at is null `+filter_by_name+``).Scan(&users.Count)
+	err = tx.QueryRow(ctx, `SELECT count(*) from users WHERE TRUE AND deleted_at is null `+filter_by_name+``).Scan(&users.Count)
 	if err != nil {
 		return &users, err
 	}
@@ -240,6 +268,37 @@ func (c *userRepo) Delete(ctx context.Context, id *user_service.UserPrimaryKey)
 	return emptypb.Empty{}, nil
 }
 
+func (c *userRepo) Restore(ctx context.Context, id *user_service.UserPrimaryKey) (emptypb.Empty, error) {
+
+	tag, err := c.db.Exec(ctx, `
+		UPDATE users SET
+		deleted_at = NULL
+		WHERE id = $1
+		`,
+		id.Id)
+
+	if err != nil {
+		return emptypb.Empty{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return emptypb.Empty{}, pgx.ErrNoRows
+	}
+	return emptypb.Empty{}, nil
+}
+
+func (c *userRepo) Purge(ctx context.Context, id *user_service.UserPrimaryKey) (emptypb.Empty, error) {
+
+	tag, err := c.db.Exec(ctx, `DELETE FROM users WHERE id = $1`, id.Id)
+
+	if err != nil {
+		return emptypb.Empty{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return emptypb.Empty{}, pgx.ErrNoRows
+	}
+	return emptypb.Empty{}, nil
+}
+
 func (c *userRepo) Check(ctx context.Context, id *user_service.UserPrimaryKey) (*user_service.CheckUserResp, error) {
 	query := `SELECT EXISTS (
                 SELECT 1
@@ -260,33 +319,42 @@ func (c *userRepo) Check(ctx context.Context, id *user_service.UserPrimaryKey) (
 	return resp, nil
 }
 
-func (c *userRepo) ChangePassword(ctx context.Context, pass *user_service.UserChangePassword) (*user_service.UserChangePasswordResp, error) {
+func (c *userRepo) ChangePassword(ctx context.Context, pass *user_service.UserChangePassword) (resp *user_service.UserChangePasswordResp, err error) {
+	err = inTx(ctx, c.db, c.txOptions, func(tx pgx.Tx) error {
+		resp, err = c.changePassword(ctx, tx, pass)
+		return err
+	})
+	return resp, err
+}
+
+func (c *userRepo) changePassword(ctx context.Context, tx pgx.Tx, pass *user_service.UserChangePassword) (*user_service.UserChangePasswordResp, error) {
 	var hashedPass string
 	var resp user_service.UserChangePasswordResp
 	query := `SELECT user_password
 	FROM users
-	WHERE user_login = $1 AND deleted_at is null`
+	WHERE user_login = $1 AND deleted_at is null
+	FOR UPDATE`
 
-	err := c.db.QueryRow(ctx, query,
+	err := tx.QueryRow(ctx, query,
 		pass.UserLogin,
 	).Scan(&hashedPass)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == pgx.ErrNoRows {
 			return nil, errors.New("incorrect login")
 		}
-		log.Println("failed to get user password from database", logger.Error(err))
+		logger.FromContext(ctx, c.log).Error("failed to get user password from database", logger.Error(err))
 		return nil, err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(hashedPass), []byte(pass.OldPassword))
+	err = hash.CompareHashAndPassword(ctx, hashedPass, pass.OldPassword)
 	if err != nil {
 		return nil, errors.New("password mismatch")
 	}
 
-	newHashedPassword, err := bcrypt.GenerateFromPassword([]byte(pass.NewPassword), bcrypt.DefaultCost)
+	newHashedPassword, err := hash.HashPassword(ctx, pass.NewPassword)
 	if err != nil {
-		log.Println("failed to generate user new password", logger.Error(err))
+		logger.FromContext(ctx, c.log).Error("failed to generate user new password", logger.Error(err))
 		return nil, err
 	}
 
@@ -295,9 +363,9 @@ func (c *userRepo) ChangePassword(ctx context.Context, pass *user_service.UserCh
 		updated_at = NOW() 
 	WHERE user_login = $2 AND deleted_at is null`
 
-	_, err = c.db.Exec(ctx, query, newHashedPassword, pass.UserLogin)
+	_, err = tx.Exec(ctx, query, newHashedPassword, pass.UserLogin)
 	if err != nil {
-		log.Println("failed to change user password in database", logger.Error(err))
+		logger.FromContext(ctx, c.log).Error("failed to change user password in database", logger.Error(err))
 		return nil, err
 	}
 	resp.Comment = "Password changed successfully"
@@ -305,6 +373,31 @@ func (c *userRepo) ChangePassword(ctx context.Context, pass *user_service.UserCh
 	return &resp, nil
 }
 
+func (c *userRepo) SetPassword(ctx context.Context, id, password string) error {
+	hashed, err := hash.HashPassword(ctx, password)
+	if err != nil {
+		logger.FromContext(ctx, c.log).Error("failed to generate user password", logger.Error(err))
+		return err
+	}
+
+	tag, err := c.db.Exec(ctx, `
+		UPDATE users SET
+		user_password = $1,
+		updated_at = NOW()
+		WHERE id = $2 AND user_password IS NULL AND deleted_at is null
+		`,
+		hashed, id)
+
+	if err != nil {
+		logger.FromContext(ctx, c.log).Error("failed to set user password in database", logger.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
 func (c *userRepo) GetByLogin(ctx context.Context, login string) (*user_service.GetUserByLogin, error) {
 	var (
 		user       user_service.GetUserByLogin
@@ -342,7 +435,7 @@ func (c *userRepo) GetByLogin(ctx context.Context, login string) (*user_service.
 	)
 
 	if err != nil {
-		log.Println("failed to scan user by LOGIN from database", logger.Error(err))
+		logger.FromContext(ctx, c.log).Error("failed to scan user by LOGIN from database", logger.Error(err))
 		return &user_service.GetUserByLogin{}, err
 	}
 
@@ -366,10 +459,273 @@ func (c *userRepo) GetPassword(ctx context.Context, login string) (string, error
 		if err == sql.ErrNoRows {
 			return "", errors.New("incorrect login")
 		} else {
-			log.Println("failed to get user password from database", logger.Error(err))
+			logger.FromContext(ctx, c.log).Error("failed to get user password from database", logger.Error(err))
 			return "", err
 		}
 	}
 
 	return hashedPass, nil
 }
+
+func (c *userRepo) GetPersonalData(ctx context.Context, id *user_service.UserPrimaryKey) (*storage.PersonalData, error) {
+	var (
+		user       user_service.GetUser
+		birthday   sql.NullString
+		created_at sql.NullString
+		updated_at sql.NullString
+		deleted_at sql.NullString
+	)
+
+	query := `SELECT
+				id,
+				user_login,
+				birthday,
+				gender,
+				fullname,
+				email,
+				phone,
+				created_at,
+				updated_at,
+				deleted_at
+			FROM users
+			WHERE id = $1`
+
+	err := c.db.QueryRow(ctx, query, id.Id).Scan(
+		&user.Id,
+		&user.UserLogin,
+		&birthday,
+		&user.Gender,
+		&user.Fullname,
+		&user.Email,
+		&user.Phone,
+		&created_at,
+		&updated_at,
+		&deleted_at)
+	if err != nil {
+		logger.FromContext(ctx, c.log).Error("failed to get user personal data from database", logger.Error(err))
+		return nil, err
+	}
+	user.Birthday = pkg.NullStringToString(birthday)
+	user.CreatedAt = pkg.NullStringToString(created_at)
+	user.UpdatedAt = pkg.NullStringToString(updated_at)
+
+	return &storage.PersonalData{
+		Profile:   &user,
+		DeletedAt: pkg.NullStringToString(deleted_at),
+	}, nil
+}
+
+// importBatchSize is the number of rows sent in a single COPY by CreateMany.
+const importBatchSize = 500
+
+// CreateMany inserts all users in one transaction using COPY. Users without
+// a password are stored with a NULL one and can only sign in after
+// SetPassword, which looks for user_password IS NULL.
+func (c *userRepo) CreateMany(ctx context.Context, reqs []*user_service.CreateUser) (users []*user_service.GetUser, err error) {
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	err = inTx(ctx, c.db, c.txOptions, func(tx pgx.Tx) error {
+		users, err = c.createMany(ctx, tx, reqs)
+		return err
+	})
+	return users, err
+}
+
+func (c *userRepo) createMany(ctx context.Context, tx pgx.Tx, reqs []*user_service.CreateUser) ([]*user_service.GetUser, error) {
+	seq, err := tx.Query(ctx, "SELECT nextval('user_external_id_seq') FROM generate_series(1, $1)", len(reqs))
+	if err != nil {
+		logger.FromContext(ctx, c.log).Error("error while generating logins", logger.Error(err))
+		return nil, err
+	}
+	logins := make([]string, 0, len(reqs))
+	for seq.Next() {
+		var nextVal int
+		if err = seq.Scan(&nextVal); err != nil {
+			seq.Close()
+			return nil, err
+		}
+		logins = append(logins, "S"+fmt.Sprintf("%05d", nextVal))
+	}
+	seq.Close()
+	if err = seq.Err(); err != nil {
+		return nil, err
+	}
+
+	users := make([]*user_service.GetUser, 0, len(reqs))
+	rows := make([][]interface{}, 0, len(reqs))
+	for i, req := range reqs {
+		var (
+			birthday interface{}
+			password interface{}
+		)
+		if req.Birthday != "" {
+			date, err := time.Parse("2006-01-02", req.Birthday)
+			if err != nil {
+				return nil, err
+			}
+			birthday = date
+		}
+		if req.UserPassword != "" {
+			hashed, err := hash.HashPassword(ctx, req.UserPassword)
+			if err != nil {
+				return nil, err
+			}
+			password = hashed
+		}
+		user := &user_service.GetUser{
+			Id:        uuid.NewString(),
+			UserLogin: logins[i],
+			Birthday:  req.Birthday,
+			Gender:    req.Gender,
+			Fullname:  req.Fullname,
+			Email:     req.Email,
+			Phone:     req.Phone,
+		}
+		users = append(users, user)
+		rows = append(rows, []interface{}{
+			user.Id,
+			user.UserLogin,
+			birthday,
+			user.Gender,
+			user.Fullname,
+			user.Email,
+			user.Phone,
+			password,
+		})
+	}
+
+	for start := 0; start < len(rows); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		_, err = tx.CopyFrom(ctx,
+			pgx.Identifier{"users"},
+			[]string{"id", "user_login", "birthday", "gender", "fullname", "email", "phone", "user_password"},
+			pgx.CopyFromRows(rows[start:end]))
+		if err != nil {
+			logger.FromContext(ctx, c.log).Error("error while copying users", logger.Error(err))
+			return nil, err
+		}
+	}
+
+	return users, nil
+}
+
+func (c *userRepo) ExistingEmails(ctx context.Context, emails []string) ([]string, error) {
+	rows, err := c.db.Query(ctx, `SELECT email FROM users WHERE lower(email) = ANY($1)`, emails)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var existing []string
+	for rows.Next() {
+		var email string
+		if err = rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		existing = append(existing, email)
+	}
+
+	return existing, rows.Err()
+}
+
+// streamFetchSize is the number of rows fetched from the cursor at a time by Stream.
+const streamFetchSize = 500
+
+// Stream walks the users matching the GetAll filters through a server side
+// cursor and calls fn for each one, so large exports never sit in memory.
+// A zero limit streams every matching user.
+func (c *userRepo) Stream(ctx context.Context, req *user_service.GetListUserRequest, fn func(*user_service.GetUser) error) error {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		args  []interface{}
+		query = `DECLARE users_export CURSOR FOR
+			SELECT
+				id,
+				user_login,
+				birthday,
+				gender,
+				fullname,
+				email,
+				phone,
+				created_at,
+				updated_at
+			FROM users
+			WHERE deleted_at IS NULL`
+	)
+	if req.Search != "" {
+		args = append(args, "%"+req.Search+"%")
+		query += fmt.Sprintf(" AND fullname ILIKE $%d", len(args))
+	}
+	query += " ORDER BY created_at, id"
+	if req.Limit > 0 {
+		offset := req.Offset
+		if offset < 1 {
+			offset = 1
+		}
+		args = append(args, (offset-1)*req.Limit, req.Limit)
+		query += fmt.Sprintf(" OFFSET $%d LIMIT $%d", len(args)-1, len(args))
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		logger.FromContext(ctx, c.log).Error("error while declaring users cursor", logger.Error(err))
+		return err
+	}
+
+	for {
+		rows, err := tx.Query(ctx, fmt.Sprintf("FETCH %d FROM users_export", streamFetchSize))
+		if err != nil {
+			return err
+		}
+
+		fetched := 0
+		for rows.Next() {
+			var (
+				user       user_service.GetUser
+				birthday   sql.NullString
+				created_at sql.NullString
+				updated_at sql.NullString
+			)
+			if err = rows.Scan(
+				&user.Id,
+				&user.UserLogin,
+				&birthday,
+				&user.Gender,
+				&user.Fullname,
+				&user.Email,
+				&user.Phone,
+				&created_at,
+				&updated_at,
+			); err != nil {
+				rows.Close()
+				return err
+			}
+			user.Birthday = pkg.NullStringToString(birthday)
+			user.CreatedAt = pkg.NullStringToString(created_at)
+			user.UpdatedAt = pkg.NullStringToString(updated_at)
+
+			if err = fn(&user); err != nil {
+				rows.Close()
+				return err
+			}
+			fetched++
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		if fetched < streamFetchSize {
+			return tx.Commit(ctx)
+		}
+	}
+}
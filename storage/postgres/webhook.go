@@ -0,0 +1,251 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"go_user_service/pkg/logger"
+	"go_user_service/storage"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+type webhookRepo struct {
+	db  DB
+	log logger.LoggerI
+}
+
+func NewWebhookRepo(db DB, log logger.LoggerI) storage.WebhookRepoI {
+	return &webhookRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+const webhookColumns = `
+	id,
+	url,
+	secret,
+	events,
+	active,
+	created_at,
+	updated_at`
+
+func scanWebhook(row pgx.Row) (*storage.Webhook, error) {
+	var (
+		hook      storage.Webhook
+		updatedAt sql.NullTime
+	)
+	if err := row.Scan(
+		&hook.Id,
+		&hook.Url,
+		&hook.Secret,
+		&hook.Events,
+		&hook.Active,
+		&hook.CreatedAt,
+		&updatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if updatedAt.Valid {
+		hook.UpdatedAt = updatedAt.Time
+	}
+	return &hook, nil
+}
+
+func (c *webhookRepo) Create(ctx context.Context, hook *storage.Webhook) error {
+	if hook.Id == "" {
+		hook.Id = uuid.NewString()
+	}
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+
+	stored, err := scanWebhook(c.db.QueryRow(ctx, `
+		INSERT INTO webhooks (
+			id,
+			url,
+			secret,
+			events,
+			active
+		) VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+webhookColumns,
+		hook.Id,
+		hook.Url,
+		hook.Secret,
+		hook.Events,
+		hook.Active,
+	))
+	if err != nil {
+		logger.FromContext(ctx, c.log).Error("error while creating webhook", logger.Error(err))
+		return err
+	}
+	*hook = *stored
+
+	return nil
+}
+
+func (c *webhookRepo) Update(ctx context.Context, hook *storage.Webhook) error {
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+
+	stored, err := scanWebhook(c.db.QueryRow(ctx, `
+		UPDATE webhooks SET
+			url = $2,
+			secret = $3,
+			events = $4,
+			active = $5,
+			updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING `+webhookColumns,
+		hook.Id,
+		hook.Url,
+		hook.Secret,
+		hook.Events,
+		hook.Active,
+	))
+	if err != nil {
+		return err
+	}
+	*hook = *stored
+
+	return nil
+}
+
+func (c *webhookRepo) Delete(ctx context.Context, id string) error {
+	tag, err := c.db.Exec(ctx, `
+		UPDATE webhooks SET
+		deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (c *webhookRepo) GetById(ctx context.Context, id string) (*storage.Webhook, error) {
+	return scanWebhook(c.db.QueryRow(ctx, `
+		SELECT `+webhookColumns+`
+		FROM webhooks
+		WHERE id = $1 AND deleted_at IS NULL`, id))
+}
+
+func (c *webhookRepo) GetAll(ctx context.Context) ([]*storage.Webhook, error) {
+	rows, err := c.db.Query(ctx, `
+		SELECT `+webhookColumns+`
+		FROM webhooks
+		WHERE deleted_at IS NULL
+		ORDER BY created_at, id`)
+	if err != nil {
+		logger.FromContext(ctx, c.log).Error("error while getting webhooks", logger.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []*storage.Webhook
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	return hooks, rows.Err()
+}
+
+func (c *webhookRepo) AddDelivery(ctx context.Context, d *storage.WebhookDelivery) error {
+	if d.Id == "" {
+		d.Id = uuid.NewString()
+	}
+
+	err := c.db.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (
+			id,
+			webhook_id,
+			event_id,
+			event_type,
+			attempt,
+			response_code,
+			error,
+			duration_ms
+		) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)
+		RETURNING created_at`,
+		d.Id,
+		d.WebhookId,
+		d.EventId,
+		d.EventType,
+		d.Attempt,
+		d.ResponseCode,
+		d.Error,
+		d.DurationMs,
+	).Scan(&d.CreatedAt)
+	if err != nil {
+		logger.FromContext(ctx, c.log).Error("error while adding webhook delivery", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (c *webhookRepo) GetDeliveries(ctx context.Context, webhookID string, offset, limit int64) ([]*storage.WebhookDelivery, int64, error) {
+	// offset 0 is what an unset proto field sends, it means the first page
+	if offset < 1 {
+		offset = 1
+	}
+
+	rows, err := c.db.Query(ctx, `
+		SELECT
+			id,
+			webhook_id,
+			event_id,
+			event_type,
+			attempt,
+			response_code,
+			COALESCE(error, ''),
+			duration_ms,
+			created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC, id
+		OFFSET $2 LIMIT $3`, webhookID, (offset-1)*limit, limit)
+	if err != nil {
+		logger.FromContext(ctx, c.log).Error("error while getting webhook deliveries", logger.Error(err))
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var deliveries []*storage.WebhookDelivery
+	for rows.Next() {
+		var d storage.WebhookDelivery
+		if err = rows.Scan(
+			&d.Id,
+			&d.WebhookId,
+			&d.EventId,
+			&d.EventType,
+			&d.Attempt,
+			&d.ResponseCode,
+			&d.Error,
+			&d.DurationMs,
+			&d.CreatedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var count int64
+	err = c.db.QueryRow(ctx, `SELECT count(*) FROM webhook_deliveries WHERE webhook_id = $1`, webhookID).Scan(&count)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, count, nil
+}
@@ -0,0 +1,113 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"go_user_service/genproto/user_events"
+	"go_user_service/storage"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+)
+
+const eventStreamKey = "events:users"
+
+// appendScript adds an event together with the id of the entry before it,
+// in one step so no other append can come in between. checkRetained uses
+// prev to tell whether anything after a resume token was trimmed.
+var appendScript = redis.NewScript(`
+local last = redis.call('XREVRANGE', KEYS[1], '+', '-', 'COUNT', 1)
+local prev = '0-0'
+if #last > 0 then
+	prev = last[1][1]
+end
+return redis.call('XADD', KEYS[1], 'MAXLEN', '~', ARGV[1], '*', 'event', ARGV[2], 'prev', prev)
+`)
+
+type eventStream struct {
+	db redis.UniversalClient
+	// block is only used for blocking reads, see New
+	block  redis.UniversalClient
+	maxLen int64
+}
+
+func (e eventStream) Append(ctx context.Context, ev *user_events.Event) (string, error) {
+	data, err := proto.Marshal(ev)
+	if err != nil {
+		return "", err
+	}
+
+	return appendScript.Run(ctx, e.db, []string{eventStreamKey}, e.maxLen, data).Text()
+}
+
+func (e eventStream) LastID(ctx context.Context) (string, error) {
+	msgs, err := e.db.XRevRangeN(ctx, eventStreamKey, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(msgs) == 0 {
+		return "0-0", nil
+	}
+	return msgs[0].ID, nil
+}
+
+func (e eventStream) Read(ctx context.Context, after string, count int, block time.Duration) ([]*storage.StreamEvent, error) {
+	if err := e.checkRetained(ctx, after); err != nil {
+		return nil, err
+	}
+
+	streams, err := e.block.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{eventStreamKey, after},
+		Count:   int64(count),
+		Block:   block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var evs []*storage.StreamEvent
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			data, _ := msg.Values["event"].(string)
+			var ev user_events.Event
+			if err = proto.Unmarshal([]byte(data), &ev); err != nil {
+				return nil, err
+			}
+			evs = append(evs, &storage.StreamEvent{Id: msg.ID, Event: &ev})
+		}
+	}
+	return evs, nil
+}
+
+// checkRetained fails when an entry newer than after was trimmed, so lost
+// events can't go unnoticed. The last trimmed entry is the prev of the
+// oldest one left; a token that points at that entry itself is still fine.
+func (e eventStream) checkRetained(ctx context.Context, after string) error {
+	if after == "0-0" || after == "0" {
+		return nil
+	}
+
+	msgs, err := e.db.XRangeN(ctx, eventStreamKey, "-", "+", 1).Result()
+	if err != nil {
+		return err
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	// entries added before prev was recorded only have their own id
+	trimmed, ok := msgs[0].Values["prev"].(string)
+	if !ok {
+		if storage.CompareStreamIDs(msgs[0].ID, after) > 0 {
+			return storage.ErrResumeTokenExpired
+		}
+		return nil
+	}
+	if storage.CompareStreamIDs(trimmed, after) > 0 {
+		return storage.ErrResumeTokenExpired
+	}
+	return nil
+}
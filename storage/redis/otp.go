@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"go_user_service/pkg/otp"
+	"go_user_service/storage"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// verifyScript compares the hashed code, counts the attempt and deletes the
+// key when the code matches or the attempts run out, all in one step so a
+// code can't be used twice or guessed in parallel.
+//
+// Returns 1 on success, 0 on mismatch, -1 when there is no code and -2 when
+// the last allowed attempt failed.
+var verifyScript = redis.NewScript(`
+local stored = redis.call('HGET', KEYS[1], 'code')
+if not stored then
+	return -1
+end
+local attempts = redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+if stored == ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return 1
+end
+if attempts >= tonumber(ARGV[2]) then
+	redis.call('DEL', KEYS[1])
+	return -2
+end
+return 0
+`)
+
+type otpStore struct {
+	db          redis.UniversalClient
+	secret      []byte
+	maxAttempts int
+	length      int
+}
+
+// otpKey namespaces codes by purpose and hides the subject, so emails never
+// show up in redis key names.
+func otpKey(secret []byte, purpose, subject string) string {
+	return "otp:" + purpose + ":" + otpHash(secret, strings.ToLower(strings.TrimSpace(subject)))
+}
+
+func otpHash(secret []byte, value string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (o otpStore) Issue(ctx context.Context, purpose, subject string, ttl time.Duration) (string, error) {
+	code, err := otp.Numeric(o.length)
+	if err != nil {
+		return "", err
+	}
+	key := otpKey(o.secret, purpose, subject)
+
+	_, err = o.db.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, "code", otpHash(o.secret, code), "attempts", 0)
+		pipe.PExpire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+func (o otpStore) Verify(ctx context.Context, purpose, subject, code string) error {
+	key := otpKey(o.secret, purpose, subject)
+
+	res, err := verifyScript.Run(ctx, o.db, []string{key}, otpHash(o.secret, code), o.maxAttempts).Int()
+	if err != nil {
+		return err
+	}
+
+	switch res {
+	case 1:
+		return nil
+	case -1:
+		return storage.ErrOTPNotFound
+	case -2:
+		return storage.ErrOTPAttemptsExceeded
+	default:
+		return storage.ErrOTPMismatch
+	}
+}
@@ -0,0 +1,51 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript refills the bucket for the time passed since it was last used
+// and takes a token from it. It reads the clock of the redis server, so
+// replicas with skewed clocks still agree on the bucket.
+//
+// Returns {1, 0} when a token was taken, or {0, µs until the next token}.
+var takeScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1]) or limit
+local ts = tonumber(bucket[2]) or now
+local rate = limit / period
+
+tokens = math.min(limit, tokens + math.max(0, now - ts) * rate)
+
+local allowed, wait = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(period / 1000))
+return {allowed, wait}
+`)
+
+type rateLimiter struct {
+	db redis.UniversalClient
+}
+
+func (r rateLimiter) Take(ctx context.Context, key string, limit int, period time.Duration) (bool, time.Duration, error) {
+	res, err := takeScript.Run(ctx, r.db, []string{"ratelimit:" + key}, limit, period.Microseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Microsecond, nil
+}
@@ -2,28 +2,136 @@ package redis
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"go_user_service/config"
+	"go_user_service/pkg/metrics"
 	"go_user_service/storage"
 	"time"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
 type Store struct {
-	db *redis.Client
+	db     redis.UniversalClient
+	stream redis.UniversalClient
+	otp    storage.OTPStoreI
+	events storage.EventStreamI
+	limits storage.RateLimiterI
 }
 
-func New(cfg config.Config) storage.IRedisStorage {
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: cfg.RedisHost + ":" + cfg.RedisPort,
-	})
+// New connects to redis in the mode set by cfg.RedisMode and pings it, so a
+// wrong address or password fails at startup rather than on the first OTP.
+// The returned store owns one client shared by all callers and a second one
+// for blocking stream reads; Close releases both.
+func New(ctx context.Context, cfg config.Config) (storage.IRedisStorage, error) {
+	opts := &redis.UniversalOptions{
+		Addrs:            cfg.RedisAddrs,
+		Username:         cfg.RedisUsername,
+		Password:         cfg.RedisPassword,
+		DB:               cfg.RedisDB,
+		MasterName:       cfg.RedisMasterName,
+		SentinelPassword: cfg.RedisSentinelPassword,
+		PoolSize:         cfg.RedisPoolSize,
+		DialTimeout:      cfg.RedisDialTimeout,
+		ReadTimeout:      cfg.RedisReadTimeout,
+		WriteTimeout:     cfg.RedisWriteTimeout,
+	}
+	if cfg.RedisTLS {
+		opts.TLSConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: cfg.RedisTLSSkipVerify,
+		}
+	}
+
+	client, err := newClient(cfg.RedisMode, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// blocking stream reads hold their connection for the whole block, so
+	// they get their own pool and can't starve OTP, cache and rate limiter
+	// calls
+	streamOpts := *opts
+	streamOpts.PoolSize = cfg.RedisStreamPoolSize
+	streamClient, err := newClient(cfg.RedisMode, &streamOpts)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	store := Store{
+		db:     client,
+		stream: streamClient,
+		otp: otpStore{
+			db:          client,
+			secret:      []byte(cfg.OTPSecret),
+			maxAttempts: cfg.OTPMaxAttempts,
+			length:      cfg.OTPLength,
+		},
+		events: eventStream{
+			db:     client,
+			block:  streamClient,
+			maxLen: cfg.EventStreamMaxLen,
+		},
+		limits: rateLimiter{db: client},
+	}
+	if err := store.Ping(ctx); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("redis ping: %w", err)
+	}
+
+	return store, nil
+}
+
+// newClient returns an instrumented client for mode.
+func newClient(mode string, opts *redis.UniversalOptions) (redis.UniversalClient, error) {
+	var client redis.UniversalClient
+	switch mode {
+	case "", "single":
+		client = redis.NewClient(opts.Simple())
+	case "sentinel":
+		if opts.MasterName == "" {
+			return nil, fmt.Errorf("REDIS_MASTER_NAME is required in sentinel mode")
+		}
+		client = redis.NewFailoverClient(opts.Failover())
+	case "cluster":
+		client = redis.NewClusterClient(opts.Cluster())
+	default:
+		return nil, fmt.Errorf("unknown redis mode %q", mode)
+	}
 
-	return Store{
-		db: redisClient,
+	client.AddHook(metrics.RedisHook{})
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		client.Close()
+		return nil, err
 	}
+	return client, nil
 }
 
-func (s Store) SetX(ctx context.Context, key string, value interface{}, duration time.Duration) error {
+func (s Store) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx).Err()
+}
+
+func (s Store) Close() error {
+	return errors.Join(s.db.Close(), s.stream.Close())
+}
+
+func (s Store) OTP() storage.OTPStoreI {
+	return s.otp
+}
+
+func (s Store) Events() storage.EventStreamI {
+	return s.events
+}
+
+func (s Store) RateLimiter() storage.RateLimiterI {
+	return s.limits
+}
+
+func (s Store) SetX(ctx context.Context, key string, value string, duration time.Duration) error {
 	statusCmd := s.db.SetEx(ctx, key, value, duration)
 	if statusCmd.Err() != nil {
 		return statusCmd.Err()
@@ -32,11 +140,15 @@ func (s Store) SetX(ctx context.Context, key string, value interface{}, duration
 	return nil
 }
 
-func (s Store) Get(ctx context.Context, key string) (interface{}, error) {
+func (s Store) SetNX(ctx context.Context, key string, value string, duration time.Duration) (bool, error) {
+	return s.db.SetNX(ctx, key, value, duration).Result()
+}
+
+func (s Store) Get(ctx context.Context, key string) (string, error) {
 	resp := s.db.Get(ctx, key)
 
 	if resp.Err() != nil {
-		return nil, resp.Err()
+		return "", resp.Err()
 	}
 	return resp.Val(), nil
 }
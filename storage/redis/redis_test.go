@@ -0,0 +1,33 @@
+package redis
+
+import (
+	"context"
+	"go_user_service/config"
+	"go_user_service/storage"
+	"go_user_service/storage/storagetest"
+	"os"
+	"testing"
+)
+
+// TestRedis runs the redis suite against the server at TEST_REDIS_ADDR,
+// e.g. localhost:6379. Keys are random, so a shared server works.
+func TestRedis(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+
+	cfg := config.Load()
+	cfg.RedisMode = "single"
+	cfg.RedisAddrs = []string{addr}
+
+	store, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	storagetest.RunRedis(t, func(t *testing.T) storage.IRedisStorage {
+		return store
+	})
+}
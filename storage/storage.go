@@ -2,7 +2,10 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"go_user_service/genproto/admin_service"
+	"go_user_service/genproto/task_service"
+	"go_user_service/genproto/user_events"
 	"go_user_service/genproto/user_service"
 
 	"time"
@@ -12,8 +15,15 @@ import (
 
 type StorageI interface {
 	CloseDB()
+	// Ping checks that the database can still be reached.
+	Ping(context.Context) error
+	// WithTx runs fn with a StorageI whose repos share a single transaction.
+	WithTx(context.Context, func(StorageI) error) error
 	Admin() AdminRepoI
 	User() UserRepoI
+	Outbox() OutboxRepoI
+	Webhook() WebhookRepoI
+	Task() TaskRepoI
 	Redis() IRedisStorage
 }
 
@@ -34,14 +44,193 @@ type UserRepoI interface {
 	GetAll(context.Context, *user_service.GetListUserRequest) (*user_service.GetListUserResponse, error)
 	GetById(context.Context, *user_service.UserPrimaryKey) (*user_service.GetUser, error)
 	Delete(context.Context, *user_service.UserPrimaryKey) (emptypb.Empty, error)
+	// Restore undoes Delete and Purge removes the user for good. Both
+	// return pgx.ErrNoRows when there is no such user.
+	Restore(context.Context, *user_service.UserPrimaryKey) (emptypb.Empty, error)
+	Purge(context.Context, *user_service.UserPrimaryKey) (emptypb.Empty, error)
 	Check(context.Context, *user_service.UserPrimaryKey) (*user_service.CheckUserResp, error)
 	ChangePassword(context.Context, *user_service.UserChangePassword) (*user_service.UserChangePasswordResp, error)
+	// SetPassword sets the password of a user that has none yet, such as
+	// an imported one. It returns pgx.ErrNoRows when there is no such
+	// active user without a password.
+	SetPassword(ctx context.Context, id, password string) error
 	GetByLogin(context.Context, string) (*user_service.GetUserByLogin, error)
 	GetPassword(context.Context, string) (string, error)
+	GetPersonalData(context.Context, *user_service.UserPrimaryKey) (*PersonalData, error)
+	CreateMany(context.Context, []*user_service.CreateUser) ([]*user_service.GetUser, error)
+	ExistingEmails(context.Context, []string) ([]string, error)
+	Stream(context.Context, *user_service.GetListUserRequest, func(*user_service.GetUser) error) error
+}
+
+type TaskRepoI interface {
+	Create(context.Context, *task_service.CreateTask) (*task_service.GetTask, error)
+	// GetById, GetByExternalId, Update, ChangeStatus and Delete return
+	// pgx.ErrNoRows for unknown and deleted tasks.
+	GetById(context.Context, *task_service.TaskPrimaryKey) (*task_service.GetTask, error)
+	GetByExternalId(context.Context, *task_service.TaskPrimaryKey) (*task_service.GetTask, error)
+	Update(context.Context, *task_service.UpdateTask) (*task_service.GetTask, error)
+	// ChangeStatus returns the status the task had before.
+	ChangeStatus(context.Context, *task_service.TaskChangeStatus) (string, error)
+	Delete(context.Context, *task_service.TaskPrimaryKey) (emptypb.Empty, error)
+	GetAll(context.Context, *task_service.GetListTaskRequest) (*task_service.GetListTaskResponse, error)
+	// DeleteByOwner deletes the active tasks of a user, RestoreByOwner
+	// brings back only the tasks deleted that way and PurgeByOwner removes
+	// all of them. Each returns the number of tasks changed.
+	DeleteByOwner(ctx context.Context, ownerID string) (int64, error)
+	RestoreByOwner(ctx context.Context, ownerID string) (int64, error)
+	PurgeByOwner(ctx context.Context, ownerID string) (int64, error)
+}
+
+// PersonalData is everything the service keeps about a single account.
+// It backs the data subject access export, so any new per-user table
+// should be added here as well.
+type PersonalData struct {
+	Profile   *user_service.GetUser `json:"profile"`
+	DeletedAt string                `json:"deleted_at,omitempty"`
+}
+
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxDead    = "dead"
+)
+
+// OutboxMessage is a unit of work, such as an email, that is written in the
+// same transaction as the change it belongs to and delivered afterwards by
+// the outbox dispatcher.
+type OutboxMessage struct {
+	Id          string
+	Kind        string
+	Payload     []byte
+	Status      string
+	Attempts    int
+	LastError   string
+	AvailableAt time.Time
+	CreatedAt   time.Time
+	SentAt      time.Time
+}
+
+type OutboxListRequest struct {
+	Offset int64
+	Limit  int64
+	// Status and Kind filter the list when set
+	Status string
+	Kind   string
+}
+
+type OutboxRepoI interface {
+	// Add stores a pending message. Id is generated when empty.
+	Add(context.Context, *OutboxMessage) error
+	// Claim returns up to limit pending messages that are due, counts the
+	// attempt and hides them from other dispatchers for lease.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*OutboxMessage, error)
+	// MarkSent records the delivery and clears the payload, which may hold
+	// a one time code.
+	MarkSent(ctx context.Context, id string) error
+	// MarkFailed records a failed attempt. The message is retried after
+	// retryIn, or moved to the dead letters when dead is set.
+	MarkFailed(ctx context.Context, id, lastError string, retryIn time.Duration, dead bool) error
+	List(context.Context, *OutboxListRequest) ([]*OutboxMessage, int64, error)
+	// Retry makes the given messages, or every dead one when ids is empty,
+	// pending again with a fresh attempt count. Sent messages are left alone.
+	Retry(ctx context.Context, ids []string) (int64, error)
+	// PurgeSent deletes the messages sent before the given time.
+	PurgeSent(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Webhook is an endpoint that receives signed lifecycle events. An empty
+// Events list subscribes it to every event.
+type Webhook struct {
+	Id        string
+	Url       string
+	Secret    string
+	Events    []string
+	Active    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// WebhookDelivery is one attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	Id           string
+	WebhookId    string
+	EventId      string
+	EventType    string
+	Attempt      int
+	ResponseCode int
+	Error        string
+	DurationMs   int64
+	CreatedAt    time.Time
+}
+
+type WebhookRepoI interface {
+	// Create stores a webhook. Id is generated when empty.
+	Create(context.Context, *Webhook) error
+	Update(context.Context, *Webhook) error
+	Delete(ctx context.Context, id string) error
+	// GetById returns pgx.ErrNoRows for unknown and deleted webhooks.
+	GetById(ctx context.Context, id string) (*Webhook, error)
+	GetAll(context.Context) ([]*Webhook, error)
+	AddDelivery(context.Context, *WebhookDelivery) error
+	// GetDeliveries lists the attempts for a webhook, newest first.
+	GetDeliveries(ctx context.Context, webhookID string, offset, limit int64) ([]*WebhookDelivery, int64, error)
 }
 
 type IRedisStorage interface {
-	SetX(context.Context, string, interface{}, time.Duration) error
-	Get(context.Context, string) (interface{}, error)
+	Ping(context.Context) error
+	Close() error
+	SetX(context.Context, string, string, time.Duration) error
+	// SetNX sets the key only when it doesn't exist and reports whether
+	// it did.
+	SetNX(context.Context, string, string, time.Duration) (bool, error)
+	Get(context.Context, string) (string, error)
 	Del(context.Context, string) error
+	OTP() OTPStoreI
+	Events() EventStreamI
+	RateLimiter() RateLimiterI
+}
+
+var (
+	ErrOTPNotFound         = errors.New("otp code expired or was not requested")
+	ErrOTPMismatch         = errors.New("incorrect otp code")
+	ErrOTPAttemptsExceeded = errors.New("too many incorrect otp codes, request a new one")
+)
+
+var ErrResumeTokenExpired = errors.New("resume token is older than the retained changes, reload and watch again")
+
+// StreamEvent is an event together with its position in the stream.
+type StreamEvent struct {
+	Id    string
+	Event *user_events.Event
+}
+
+// EventStreamI is a capped log of lifecycle events shared by all replicas.
+// Positions are redis stream ids ("<ms>-<seq>") and only grow.
+type EventStreamI interface {
+	Append(context.Context, *user_events.Event) (string, error)
+	// LastID returns the position of the newest event, "0-0" when empty.
+	LastID(context.Context) (string, error)
+	// Read returns up to count events after position after, waiting up to
+	// block when there are none yet. It returns ErrResumeTokenExpired when
+	// an event after that position was trimmed.
+	Read(ctx context.Context, after string, count int, block time.Duration) ([]*StreamEvent, error)
+}
+
+// RateLimiterI is a token bucket per key shared by all replicas. A bucket
+// holds up to limit tokens and refills at limit per period.
+type RateLimiterI interface {
+	// Take removes one token from the bucket of key. When the bucket is
+	// empty it returns false and how long until the next token is added.
+	Take(ctx context.Context, key string, limit int, period time.Duration) (bool, time.Duration, error)
+}
+
+// OTPStoreI keeps one-time codes per purpose (register, password reset...)
+// and subject (usually an email). Codes are stored hashed and are consumed
+// by a successful Verify, or dropped after too many wrong attempts.
+type OTPStoreI interface {
+	// Issue generates a new code for subject, replacing any previous one.
+	Issue(ctx context.Context, purpose, subject string, ttl time.Duration) (string, error)
+	// Verify checks code atomically. It returns ErrOTPNotFound,
+	// ErrOTPMismatch or ErrOTPAttemptsExceeded when the code is not accepted.
+	Verify(ctx context.Context, purpose, subject, code string) error
 }
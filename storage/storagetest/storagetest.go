@@ -0,0 +1,1050 @@
+// Package storagetest is the behaviour every storage.StorageI and
+// storage.IRedisStorage implementation has to share. Implementations call
+// RunStorage and RunRedis from their own tests, so the postgres, redis and
+// memory packages are all held to the same checks and can't drift apart.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"go_user_service/genproto/admin_service"
+	"go_user_service/genproto/task_service"
+	"go_user_service/genproto/user_events"
+	"go_user_service/genproto/user_service"
+	"go_user_service/storage"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// RunStorage runs the conformance suite against stores made by newStore.
+// Every subtest gets a fresh store; data written by one subtest must not
+// be relied upon by another, so a shared database works too.
+func RunStorage(t *testing.T, newStore func(t *testing.T) storage.StorageI) {
+	for _, tc := range []struct {
+		name string
+		fn   func(*testing.T, storage.StorageI)
+	}{
+		{"UserCreateAndGet", testUserCreateAndGet},
+		{"UserDuplicateEmail", testUserDuplicateEmail},
+		{"UserUpdate", testUserUpdate},
+		{"UserDelete", testUserDelete},
+		{"UserRestoreAndPurge", testUserRestoreAndPurge},
+		{"UserChangePassword", testUserChangePassword},
+		{"UserSetPassword", testUserSetPassword},
+		{"UserGetAll", testUserGetAll},
+		{"UserCreateMany", testUserCreateMany},
+		{"UserStream", testUserStream},
+		{"WithTxRollback", testWithTxRollback},
+		{"WithTxCommit", testWithTxCommit},
+		{"AdminLifecycle", testAdminLifecycle},
+		{"OutboxDelivery", testOutboxDelivery},
+		{"OutboxDeadAndRetry", testOutboxDeadAndRetry},
+		{"OutboxRollback", testOutboxRollback},
+		{"WebhookLifecycle", testWebhookLifecycle},
+		{"WebhookDeliveries", testWebhookDeliveries},
+		{"TaskLifecycle", testTaskLifecycle},
+		{"TaskOwnerCascade", testTaskOwnerCascade},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newStore(t))
+		})
+	}
+}
+
+// RunRedis runs the conformance suite against redis stores made by newRedis.
+func RunRedis(t *testing.T, newRedis func(t *testing.T) storage.IRedisStorage) {
+	t.Run("SetGetDel", func(t *testing.T) {
+		testRedisSetGetDel(t, newRedis(t))
+	})
+	t.Run("Expiry", func(t *testing.T) {
+		testRedisExpiry(t, newRedis(t))
+	})
+	t.Run("OTPVerify", func(t *testing.T) {
+		testOTPVerify(t, newRedis(t))
+	})
+	t.Run("OTPAttempts", func(t *testing.T) {
+		testOTPAttempts(t, newRedis(t))
+	})
+	t.Run("EventStream", func(t *testing.T) {
+		testEventStream(t, newRedis(t))
+	})
+	t.Run("RateLimit", func(t *testing.T) {
+		testRateLimit(t, newRedis(t))
+	})
+}
+
+func uniqueEmail() string {
+	return strings.ReplaceAll(uuid.NewString(), "-", "")[:20] + "@gmail.com"
+}
+
+func newUser(fullname string) *user_service.CreateUser {
+	return &user_service.CreateUser{
+		Birthday:     "2000-01-02",
+		Gender:       "male",
+		Fullname:     fullname,
+		Email:        uniqueEmail(),
+		Phone:        "+998901234567",
+		UserPassword: "secret",
+	}
+}
+
+func mustCreateUser(t *testing.T, strg storage.StorageI, req *user_service.CreateUser) *user_service.GetUser {
+	t.Helper()
+	user, err := strg.User().Create(context.Background(), req)
+	if err != nil {
+		t.Fatalf("User().Create: %v", err)
+	}
+	return user
+}
+
+func testUserCreateAndGet(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	req := newUser("Create And Get")
+
+	created := mustCreateUser(t, strg, req)
+	if created.Id == "" || !strings.HasPrefix(created.UserLogin, "S") {
+		t.Fatalf("Create returned id %q login %q", created.Id, created.UserLogin)
+	}
+	if created.Email != req.Email || created.Fullname != req.Fullname || created.Gender != req.Gender {
+		t.Fatalf("Create returned %v for %v", created, req)
+	}
+	if !strings.HasPrefix(created.Birthday, req.Birthday) {
+		t.Fatalf("Create returned birthday %q, want %q", created.Birthday, req.Birthday)
+	}
+
+	got, err := strg.User().GetById(ctx, &user_service.UserPrimaryKey{Id: created.Id})
+	if err != nil {
+		t.Fatalf("GetById: %v", err)
+	}
+	if got.Id != created.Id || got.UserLogin != created.UserLogin || got.Email != created.Email {
+		t.Fatalf("GetById returned %v, want %v", got, created)
+	}
+
+	byLogin, err := strg.User().GetByLogin(ctx, created.UserLogin)
+	if err != nil {
+		t.Fatalf("GetByLogin: %v", err)
+	}
+	if byLogin.Id != created.Id || byLogin.UserPassword == "" || byLogin.UserPassword == req.UserPassword {
+		t.Fatalf("GetByLogin must return the hashed password, got %q", byLogin.UserPassword)
+	}
+
+	password, err := strg.User().GetPassword(ctx, created.UserLogin)
+	if err != nil || password != byLogin.UserPassword {
+		t.Fatalf("GetPassword returned %q, %v", password, err)
+	}
+
+	check, err := strg.User().Check(ctx, &user_service.UserPrimaryKey{Id: created.Id})
+	if err != nil || !check.Check {
+		t.Fatalf("Check returned %v, %v", check, err)
+	}
+
+	if _, err = strg.User().GetById(ctx, &user_service.UserPrimaryKey{Id: uuid.NewString()}); err == nil {
+		t.Fatal("GetById of an unknown id must fail")
+	}
+}
+
+func testUserDuplicateEmail(t *testing.T, strg storage.StorageI) {
+	req := newUser("Duplicate")
+	mustCreateUser(t, strg, req)
+
+	again := newUser("Duplicate Again")
+	again.Email = req.Email
+	if _, err := strg.User().Create(context.Background(), again); err == nil {
+		t.Fatal("Create with a taken email must fail")
+	}
+}
+
+func testUserUpdate(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	created := mustCreateUser(t, strg, newUser("Before Update"))
+
+	email := uniqueEmail()
+	updated, err := strg.User().Update(ctx, &user_service.UpdateUser{
+		Id:       created.Id,
+		Birthday: "1999-09-09",
+		Gender:   "female",
+		Fullname: "After Update",
+		Email:    email,
+		Phone:    "+998907654321",
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Fullname != "After Update" || updated.Email != email || updated.Gender != "female" || updated.UpdatedAt == "" {
+		t.Fatalf("Update returned %v", updated)
+	}
+	if updated.UserLogin != created.UserLogin {
+		t.Fatalf("Update changed login from %q to %q", created.UserLogin, updated.UserLogin)
+	}
+}
+
+func testUserDelete(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	created := mustCreateUser(t, strg, newUser("To Delete"))
+	key := &user_service.UserPrimaryKey{Id: created.Id}
+
+	if _, err := strg.User().Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if _, err := strg.User().GetById(ctx, key); err == nil {
+		t.Fatal("GetById of a deleted user must fail")
+	}
+	if _, err := strg.User().GetByLogin(ctx, created.UserLogin); err == nil {
+		t.Fatal("GetByLogin of a deleted user must fail")
+	}
+	check, err := strg.User().Check(ctx, key)
+	if err != nil || check.Check {
+		t.Fatalf("Check of a deleted user returned %v, %v", check, err)
+	}
+
+	data, err := strg.User().GetPersonalData(ctx, key)
+	if err != nil {
+		t.Fatalf("GetPersonalData of a deleted user: %v", err)
+	}
+	if data.Profile.Id != created.Id || data.DeletedAt == "" {
+		t.Fatalf("GetPersonalData returned %v", data)
+	}
+}
+
+func testUserRestoreAndPurge(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	created := mustCreateUser(t, strg, newUser("To Restore"))
+	key := &user_service.UserPrimaryKey{Id: created.Id}
+
+	if _, err := strg.User().Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := strg.User().Restore(ctx, key); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if _, err := strg.User().GetById(ctx, key); err != nil {
+		t.Fatalf("GetById of a restored user: %v", err)
+	}
+
+	if _, err := strg.User().Purge(ctx, key); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if _, err := strg.User().GetPersonalData(ctx, key); err == nil {
+		t.Fatal("GetPersonalData of a purged user must fail")
+	}
+
+	missing := &user_service.UserPrimaryKey{Id: uuid.NewString()}
+	if _, err := strg.User().Restore(ctx, missing); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("Restore of an unknown user must return pgx.ErrNoRows, got %v", err)
+	}
+	if _, err := strg.User().Purge(ctx, key); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("Purge of a purged user must return pgx.ErrNoRows, got %v", err)
+	}
+}
+
+func testUserChangePassword(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	created := mustCreateUser(t, strg, newUser("Change Password"))
+
+	_, err := strg.User().ChangePassword(ctx, &user_service.UserChangePassword{
+		UserLogin:   created.UserLogin,
+		OldPassword: "wrong",
+		NewPassword: "new-secret",
+	})
+	if err == nil {
+		t.Fatal("ChangePassword with a wrong old password must fail")
+	}
+
+	before, _ := strg.User().GetPassword(ctx, created.UserLogin)
+	resp, err := strg.User().ChangePassword(ctx, &user_service.UserChangePassword{
+		UserLogin:   created.UserLogin,
+		OldPassword: "secret",
+		NewPassword: "new-secret",
+	})
+	if err != nil || resp.Comment == "" {
+		t.Fatalf("ChangePassword returned %v, %v", resp, err)
+	}
+	after, _ := strg.User().GetPassword(ctx, created.UserLogin)
+	if after == before {
+		t.Fatal("ChangePassword did not change the stored hash")
+	}
+
+	_, err = strg.User().ChangePassword(ctx, &user_service.UserChangePassword{
+		UserLogin:   "no-such-login",
+		OldPassword: "secret",
+		NewPassword: "new-secret",
+	})
+	if err == nil {
+		t.Fatal("ChangePassword with an unknown login must fail")
+	}
+}
+
+func testUserSetPassword(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	invited := newUser("Set Password")
+	invited.UserPassword = ""
+	users, err := strg.User().CreateMany(ctx, []*user_service.CreateUser{invited})
+	if err != nil {
+		t.Fatalf("CreateMany: %v", err)
+	}
+
+	if err = strg.User().SetPassword(ctx, users[0].Id, "first-secret"); err != nil {
+		t.Fatalf("SetPassword: %v", err)
+	}
+	if hashed, err := strg.User().GetPassword(ctx, users[0].UserLogin); err != nil || hashed == "" || hashed == "first-secret" {
+		t.Fatalf("GetPassword returned %q, %v, want a hash", hashed, err)
+	}
+	if err = strg.User().SetPassword(ctx, users[0].Id, "second-secret"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("second SetPassword returned %v, want pgx.ErrNoRows", err)
+	}
+
+	created := mustCreateUser(t, strg, newUser("Has Password"))
+	if err = strg.User().SetPassword(ctx, created.Id, "other"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("SetPassword of a user with a password returned %v, want pgx.ErrNoRows", err)
+	}
+	if err = strg.User().SetPassword(ctx, uuid.NewString(), "other"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("SetPassword of an unknown user returned %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func testUserGetAll(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	tag := strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	for i := 0; i < 3; i++ {
+		mustCreateUser(t, strg, newUser("List "+tag))
+	}
+
+	resp, err := strg.User().GetAll(ctx, &user_service.GetListUserRequest{Offset: 1, Limit: 2, Search: tag})
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if resp.Count != 3 || len(resp.Users) != 2 {
+		t.Fatalf("GetAll returned count %d and %d users, want 3 and 2", resp.Count, len(resp.Users))
+	}
+
+	resp, err = strg.User().GetAll(ctx, &user_service.GetListUserRequest{Offset: 2, Limit: 2, Search: tag})
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if resp.Count != 3 || len(resp.Users) != 1 {
+		t.Fatalf("GetAll second page returned count %d and %d users, want 3 and 1", resp.Count, len(resp.Users))
+	}
+}
+
+func testUserCreateMany(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	first, second := newUser("Many One"), newUser("Many Two")
+	second.UserPassword = ""
+
+	users, err := strg.User().CreateMany(ctx, []*user_service.CreateUser{first, second})
+	if err != nil {
+		t.Fatalf("CreateMany: %v", err)
+	}
+	if len(users) != 2 || users[0].Email != first.Email || users[1].Email != second.Email {
+		t.Fatalf("CreateMany returned %v", users)
+	}
+	for _, user := range users {
+		if _, err = strg.User().GetById(ctx, &user_service.UserPrimaryKey{Id: user.Id}); err != nil {
+			t.Fatalf("GetById after CreateMany: %v", err)
+		}
+	}
+
+	existing, err := strg.User().ExistingEmails(ctx, []string{first.Email, uniqueEmail()})
+	if err != nil {
+		t.Fatalf("ExistingEmails: %v", err)
+	}
+	if len(existing) != 1 || existing[0] != first.Email {
+		t.Fatalf("ExistingEmails returned %v, want [%s]", existing, first.Email)
+	}
+
+	// a duplicate makes the whole batch fail
+	third := newUser("Many Three")
+	dup := newUser("Many Dup")
+	dup.Email = first.Email
+	if _, err = strg.User().CreateMany(ctx, []*user_service.CreateUser{third, dup}); err == nil {
+		t.Fatal("CreateMany with a taken email must fail")
+	}
+	existing, _ = strg.User().ExistingEmails(ctx, []string{third.Email})
+	if len(existing) != 0 {
+		t.Fatal("CreateMany must not keep any row of a failed batch")
+	}
+}
+
+func testUserStream(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	tag := strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	want := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		want[mustCreateUser(t, strg, newUser("Stream "+tag)).Id] = true
+	}
+
+	var got []string
+	err := strg.User().Stream(ctx, &user_service.GetListUserRequest{Search: tag}, func(user *user_service.GetUser) error {
+		got = append(got, user.Id)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Stream returned %d users, want %d", len(got), len(want))
+	}
+	for _, id := range got {
+		if !want[id] {
+			t.Fatalf("Stream returned unexpected user %s", id)
+		}
+	}
+
+	// offset 0 is what an unset proto field sends, it means the first page
+	got = got[:0]
+	err = strg.User().Stream(ctx, &user_service.GetListUserRequest{Search: tag, Limit: 2}, func(user *user_service.GetUser) error {
+		got = append(got, user.Id)
+		return nil
+	})
+	if err != nil || len(got) != 2 {
+		t.Fatalf("Stream with offset 0 returned %d users, %v, want the first 2", len(got), err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = strg.User().Stream(ctx, &user_service.GetListUserRequest{Search: tag}, func(*user_service.GetUser) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("Stream must stop on the first callback error, got %v after %d calls", err, calls)
+	}
+}
+
+func testWithTxRollback(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	var id string
+	fail := errors.New("rollback")
+
+	err := strg.WithTx(ctx, func(tx storage.StorageI) error {
+		user, err := tx.User().Create(ctx, newUser("Rolled Back"))
+		if err != nil {
+			return err
+		}
+		id = user.Id
+		return fail
+	})
+	if !errors.Is(err, fail) {
+		t.Fatalf("WithTx returned %v, want the error of fn", err)
+	}
+	if _, err = strg.User().GetById(ctx, &user_service.UserPrimaryKey{Id: id}); err == nil {
+		t.Fatal("a user created in a rolled back transaction must not exist")
+	}
+}
+
+func testWithTxCommit(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	var user *user_service.GetUser
+
+	err := strg.WithTx(ctx, func(tx storage.StorageI) error {
+		var err error
+		if user, err = tx.User().Create(ctx, newUser("Committed")); err != nil {
+			return err
+		}
+		_, err = tx.User().GetById(ctx, &user_service.UserPrimaryKey{Id: user.Id})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	if _, err = strg.User().GetById(ctx, &user_service.UserPrimaryKey{Id: user.Id}); err != nil {
+		t.Fatalf("GetById after commit: %v", err)
+	}
+}
+
+func testAdminLifecycle(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	created, err := strg.Admin().Create(ctx, &admin_service.CreateAdmin{
+		Birthday:     "1990-01-01",
+		Gender:       "female",
+		Fullname:     "Admin Lifecycle",
+		Email:        uniqueEmail(),
+		Phone:        "+998901234567",
+		UserPassword: "secret",
+	})
+	if err != nil {
+		t.Fatalf("Admin().Create: %v", err)
+	}
+	if !strings.HasPrefix(created.UserLogin, "A") {
+		t.Fatalf("admin login %q must start with A", created.UserLogin)
+	}
+	key := &admin_service.AdminPrimaryKey{Id: created.Id}
+
+	updated, err := strg.Admin().Update(ctx, &admin_service.UpdateAdmin{
+		Id:       created.Id,
+		Birthday: "1990-01-01",
+		Gender:   "female",
+		Fullname: "Admin Updated",
+		Email:    created.Email,
+		Phone:    created.Phone,
+	})
+	if err != nil || updated.Fullname != "Admin Updated" {
+		t.Fatalf("Admin().Update returned %v, %v", updated, err)
+	}
+
+	if _, err = strg.Admin().ChangePassword(ctx, &admin_service.AdminChangePassword{
+		UserLogin:   created.UserLogin,
+		OldPassword: "secret",
+		NewPassword: "new-secret",
+	}); err != nil {
+		t.Fatalf("Admin().ChangePassword: %v", err)
+	}
+
+	if _, err = strg.Admin().Delete(ctx, key); err != nil {
+		t.Fatalf("Admin().Delete: %v", err)
+	}
+	if _, err = strg.Admin().GetById(ctx, key); err == nil {
+		t.Fatal("GetById of a deleted admin must fail")
+	}
+}
+
+func testRedisSetGetDel(t *testing.T, redis storage.IRedisStorage) {
+	ctx := context.Background()
+	key := "storagetest:" + uuid.NewString()
+
+	if _, err := redis.Get(ctx, key); err == nil {
+		t.Fatal("Get of a missing key must fail")
+	}
+	if err := redis.SetX(ctx, key, "123456", time.Minute); err != nil {
+		t.Fatalf("SetX: %v", err)
+	}
+	val, err := redis.Get(ctx, key)
+	if err != nil || val != "123456" {
+		t.Fatalf("Get returned %q, %v", val, err)
+	}
+	if err = redis.Del(ctx, key); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	if _, err = redis.Get(ctx, key); err == nil {
+		t.Fatal("Get after Del must fail")
+	}
+
+	if err = redis.SetX(ctx, key, "654321", time.Minute); err != nil {
+		t.Fatalf("SetX: %v", err)
+	}
+	if ok, err := redis.SetNX(ctx, key, "other", time.Minute); err != nil || ok {
+		t.Fatalf("SetNX of an existing key returned %v, %v, want false", ok, err)
+	}
+	if err = redis.Del(ctx, key); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	if ok, err := redis.SetNX(ctx, key, "other", time.Minute); err != nil || !ok {
+		t.Fatalf("SetNX of a missing key returned %v, %v, want true", ok, err)
+	}
+}
+
+func testRedisExpiry(t *testing.T, redis storage.IRedisStorage) {
+	ctx := context.Background()
+	key := "storagetest:" + uuid.NewString()
+
+	if err := redis.SetX(ctx, key, "value", time.Second); err != nil {
+		t.Fatalf("SetX: %v", err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := redis.Get(ctx, key); err == nil {
+		t.Fatal("Get after the TTL must fail")
+	}
+}
+
+func testOTPVerify(t *testing.T, redis storage.IRedisStorage) {
+	ctx := context.Background()
+	subject := uniqueEmail()
+
+	if err := redis.OTP().Verify(ctx, "test", subject, "000000"); !errors.Is(err, storage.ErrOTPNotFound) {
+		t.Fatalf("Verify without Issue returned %v, want ErrOTPNotFound", err)
+	}
+
+	code, err := redis.OTP().Issue(ctx, "test", subject, time.Minute)
+	if err != nil || code == "" {
+		t.Fatalf("Issue returned %q, %v", code, err)
+	}
+	if err = redis.OTP().Verify(ctx, "other", subject, code); !errors.Is(err, storage.ErrOTPNotFound) {
+		t.Fatalf("Verify with another purpose returned %v, want ErrOTPNotFound", err)
+	}
+	if err = redis.OTP().Verify(ctx, "test", strings.ToUpper(subject), code); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err = redis.OTP().Verify(ctx, "test", subject, code); !errors.Is(err, storage.ErrOTPNotFound) {
+		t.Fatalf("a code must not be accepted twice, Verify returned %v", err)
+	}
+}
+
+func testOTPAttempts(t *testing.T, redis storage.IRedisStorage) {
+	ctx := context.Background()
+	subject := uniqueEmail()
+
+	code, err := redis.OTP().Issue(ctx, "test", subject, time.Minute)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	wrong := "x" + code
+
+	for {
+		err = redis.OTP().Verify(ctx, "test", subject, wrong)
+		if errors.Is(err, storage.ErrOTPAttemptsExceeded) {
+			break
+		}
+		if !errors.Is(err, storage.ErrOTPMismatch) {
+			t.Fatalf("Verify with a wrong code returned %v", err)
+		}
+	}
+	if err = redis.OTP().Verify(ctx, "test", subject, code); !errors.Is(err, storage.ErrOTPNotFound) {
+		t.Fatalf("the code must be dropped after too many attempts, Verify returned %v", err)
+	}
+}
+
+func testRateLimit(t *testing.T, redis storage.IRedisStorage) {
+	ctx := context.Background()
+	key, other := "test:"+uuid.NewString(), "test:"+uuid.NewString()
+
+	for i := 0; i < 3; i++ {
+		ok, _, err := redis.RateLimiter().Take(ctx, key, 3, time.Hour)
+		if err != nil {
+			t.Fatalf("Take: %v", err)
+		}
+		if !ok {
+			t.Fatalf("token %d of 3 was refused", i+1)
+		}
+	}
+
+	ok, retryAfter, err := redis.RateLimiter().Take(ctx, key, 3, time.Hour)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if ok {
+		t.Fatal("Take must refuse once the bucket is empty")
+	}
+	if retryAfter <= 0 || retryAfter > 20*time.Minute {
+		t.Fatalf("one token refills in at most 20m, got retry after %s", retryAfter)
+	}
+
+	if ok, _, err = redis.RateLimiter().Take(ctx, other, 3, time.Hour); err != nil || !ok {
+		t.Fatalf("buckets must be per key, Take returned %v, %v", ok, err)
+	}
+}
+
+func testEventStream(t *testing.T, redis storage.IRedisStorage) {
+	ctx := context.Background()
+	stream := redis.Events()
+
+	start, err := stream.LastID(ctx)
+	if err != nil {
+		t.Fatalf("LastID: %v", err)
+	}
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := stream.Append(ctx, &user_events.Event{Id: uuid.NewString()})
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		if len(ids) > 0 && storage.CompareStreamIDs(id, ids[len(ids)-1]) <= 0 {
+			t.Fatalf("stream ids must grow, got %s after %s", id, ids[len(ids)-1])
+		}
+		ids = append(ids, id)
+	}
+
+	if last, err := stream.LastID(ctx); err != nil || last != ids[2] {
+		t.Fatalf("LastID = %q, %v, want %q", last, err, ids[2])
+	}
+
+	// other subtests may share the stream, so only the order of ours is checked
+	got, err := stream.Read(ctx, start, 1000, 0)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	var seen []string
+	for _, ev := range got {
+		for _, id := range ids {
+			if ev.Id == id {
+				seen = append(seen, id)
+			}
+		}
+	}
+	if strings.Join(seen, ",") != strings.Join(ids, ",") {
+		t.Fatalf("Read after %s returned %v, want %v", start, seen, ids)
+	}
+
+	got, err = stream.Read(ctx, ids[1], 1000, 0)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(got) == 0 || got[0].Id != ids[2] {
+		t.Fatalf("resuming after %s must start at %s", ids[1], ids[2])
+	}
+
+	// a blocking read returns as soon as something is appended
+	done := make(chan []*storage.StreamEvent, 1)
+	go func() {
+		evs, _ := stream.Read(ctx, ids[2], 1, 5*time.Second)
+		done <- evs
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if _, err = stream.Append(ctx, &user_events.Event{Id: uuid.NewString()}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	select {
+	case evs := <-done:
+		if len(evs) != 1 {
+			t.Fatalf("blocking Read returned %d events, want 1", len(evs))
+		}
+	case <-time.After(4 * time.Second):
+		t.Fatal("blocking Read did not return after Append")
+	}
+}
+
+// claimOne claims due messages until id shows up. Other subtests may share
+// the outbox, so their messages are skipped rather than expected absent.
+func claimOne(t *testing.T, strg storage.StorageI, id string) *storage.OutboxMessage {
+	t.Helper()
+	msgs, err := strg.Outbox().Claim(context.Background(), 1000, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	for _, msg := range msgs {
+		if msg.Id == id {
+			return msg
+		}
+	}
+	return nil
+}
+
+func newOutboxMessage(t *testing.T, strg storage.StorageI) *storage.OutboxMessage {
+	t.Helper()
+	msg := &storage.OutboxMessage{
+		Kind:    "storagetest",
+		Payload: []byte(`{"to": "someone"}`),
+	}
+	if err := strg.Outbox().Add(context.Background(), msg); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if msg.Id == "" || msg.Status != storage.OutboxPending {
+		t.Fatalf("Add returned %+v, want a pending message with an id", msg)
+	}
+	return msg
+}
+
+func testOutboxDelivery(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	msg := newOutboxMessage(t, strg)
+
+	claimed := claimOne(t, strg, msg.Id)
+	if claimed == nil {
+		t.Fatalf("new message was not claimed")
+	}
+	if claimed.Attempts != 1 || string(claimed.Payload) == "" {
+		t.Fatalf("claimed %+v, want attempt 1 with the payload", claimed)
+	}
+	if claimOne(t, strg, msg.Id) != nil {
+		t.Fatalf("a leased message was claimed twice")
+	}
+
+	if err := strg.Outbox().MarkFailed(ctx, msg.Id, "smtp down", 0, false); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+	claimed = claimOne(t, strg, msg.Id)
+	if claimed == nil || claimed.Attempts != 2 || claimed.LastError != "smtp down" {
+		t.Fatalf("retried message claimed as %+v", claimed)
+	}
+
+	if err := strg.Outbox().MarkSent(ctx, msg.Id); err != nil {
+		t.Fatalf("MarkSent: %v", err)
+	}
+	msgs, count, err := strg.Outbox().List(ctx, &storage.OutboxListRequest{Offset: 1, Limit: 1000, Status: storage.OutboxSent})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var found *storage.OutboxMessage
+	for _, m := range msgs {
+		if m.Id == msg.Id {
+			found = m
+		}
+	}
+	if found == nil || count < 1 || found.SentAt.IsZero() || found.LastError != "" {
+		t.Fatalf("sent message listed as %+v (count %d)", found, count)
+	}
+	if string(found.Payload) != "{}" {
+		t.Fatalf("sent message kept its payload %s", found.Payload)
+	}
+	if first, _, err := strg.Outbox().List(ctx, &storage.OutboxListRequest{Limit: 1, Status: storage.OutboxSent}); err != nil || len(first) != 1 {
+		t.Fatalf("List with offset 0 must return the first page, got %d messages, %v", len(first), err)
+	}
+	if n, err := strg.Outbox().Retry(ctx, []string{msg.Id}); err != nil || n != 0 {
+		t.Fatalf("Retry of a sent message returned %d, %v, want 0", n, err)
+	}
+
+	if n, err := strg.Outbox().PurgeSent(ctx, time.Now().Add(time.Minute)); err != nil || n < 1 {
+		t.Fatalf("PurgeSent returned %d, %v, want the sent message purged", n, err)
+	}
+	msgs, _, err = strg.Outbox().List(ctx, &storage.OutboxListRequest{Offset: 1, Limit: 1000, Status: storage.OutboxSent})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	for _, m := range msgs {
+		if m.Id == msg.Id {
+			t.Fatal("PurgeSent kept a message sent before the cutoff")
+		}
+	}
+}
+
+func testOutboxDeadAndRetry(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	msg := newOutboxMessage(t, strg)
+
+	if claimOne(t, strg, msg.Id) == nil {
+		t.Fatalf("new message was not claimed")
+	}
+	if err := strg.Outbox().MarkFailed(ctx, msg.Id, "mailbox unavailable", 0, true); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+	if claimOne(t, strg, msg.Id) != nil {
+		t.Fatalf("a dead message was claimed")
+	}
+
+	n, err := strg.Outbox().Retry(ctx, []string{msg.Id})
+	if err != nil || n != 1 {
+		t.Fatalf("Retry returned %d, %v, want 1", n, err)
+	}
+	claimed := claimOne(t, strg, msg.Id)
+	if claimed == nil || claimed.Attempts != 1 {
+		t.Fatalf("retried message claimed as %+v, want a fresh attempt count", claimed)
+	}
+}
+
+func testOutboxRollback(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	var id string
+	err := strg.WithTx(ctx, func(tx storage.StorageI) error {
+		msg := newOutboxMessage(t, tx)
+		id = msg.Id
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithTx returned %v", err)
+	}
+	if claimOne(t, strg, id) != nil {
+		t.Fatalf("a message added in a rolled back transaction was claimed")
+	}
+}
+
+func findWebhook(hooks []*storage.Webhook, id string) *storage.Webhook {
+	for _, hook := range hooks {
+		if hook.Id == id {
+			return hook
+		}
+	}
+	return nil
+}
+
+func testWebhookLifecycle(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+
+	hook := &storage.Webhook{
+		Url:    "https://example.com/hooks",
+		Secret: "secret",
+		Events: []string{"user_created"},
+		Active: true,
+	}
+	if err := strg.Webhook().Create(ctx, hook); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if hook.Id == "" || hook.CreatedAt.IsZero() {
+		t.Fatalf("Create returned %+v, want id and created_at", hook)
+	}
+
+	got, err := strg.Webhook().GetById(ctx, hook.Id)
+	if err != nil {
+		t.Fatalf("GetById: %v", err)
+	}
+	if got.Url != hook.Url || got.Secret != "secret" || len(got.Events) != 1 || got.Events[0] != "user_created" || !got.Active {
+		t.Fatalf("GetById returned %+v", got)
+	}
+
+	got.Events = nil
+	got.Active = false
+	if err = strg.Webhook().Update(ctx, got); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got.UpdatedAt.IsZero() || len(got.Events) != 0 || got.Active {
+		t.Fatalf("Update returned %+v", got)
+	}
+
+	hooks, err := strg.Webhook().GetAll(ctx)
+	if err != nil || findWebhook(hooks, hook.Id) == nil {
+		t.Fatalf("GetAll did not return the webhook, err %v", err)
+	}
+
+	if err = strg.Webhook().Delete(ctx, hook.Id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err = strg.Webhook().GetById(ctx, hook.Id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetById after Delete returned %v, want pgx.ErrNoRows", err)
+	}
+	if err = strg.Webhook().Update(ctx, got); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("Update after Delete returned %v, want pgx.ErrNoRows", err)
+	}
+	if err = strg.Webhook().Delete(ctx, hook.Id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("second Delete returned %v, want pgx.ErrNoRows", err)
+	}
+	hooks, err = strg.Webhook().GetAll(ctx)
+	if err != nil || findWebhook(hooks, hook.Id) != nil {
+		t.Fatalf("GetAll returned a deleted webhook, err %v", err)
+	}
+}
+
+func testWebhookDeliveries(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+
+	hook := &storage.Webhook{Url: "https://example.com/hooks", Secret: "secret", Active: true}
+	if err := strg.Webhook().Create(ctx, hook); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		d := &storage.WebhookDelivery{
+			WebhookId:    hook.Id,
+			EventId:      "event-1",
+			EventType:    "user_created",
+			Attempt:      attempt,
+			ResponseCode: 500,
+			Error:        "webhook endpoint answered 500 Internal Server Error",
+			DurationMs:   12,
+		}
+		if err := strg.Webhook().AddDelivery(ctx, d); err != nil {
+			t.Fatalf("AddDelivery: %v", err)
+		}
+		if d.Id == "" || d.CreatedAt.IsZero() {
+			t.Fatalf("AddDelivery returned %+v, want id and created_at", d)
+		}
+	}
+
+	deliveries, count, err := strg.Webhook().GetDeliveries(ctx, hook.Id, 1, 2)
+	if err != nil {
+		t.Fatalf("GetDeliveries: %v", err)
+	}
+	if count != 3 || len(deliveries) != 2 {
+		t.Fatalf("GetDeliveries returned %d of %d, want 2 of 3", len(deliveries), count)
+	}
+	if deliveries[0].Attempt != 3 || deliveries[0].ResponseCode != 500 || deliveries[0].Error == "" {
+		t.Fatalf("newest delivery is %+v, want attempt 3", deliveries[0])
+	}
+	if first, _, err := strg.Webhook().GetDeliveries(ctx, hook.Id, 0, 1); err != nil || len(first) != 1 || first[0].Attempt != 3 {
+		t.Fatalf("GetDeliveries with offset 0 must return the first page, got %v, %v", first, err)
+	}
+
+	if err = strg.Webhook().AddDelivery(ctx, &storage.WebhookDelivery{WebhookId: uuid.NewString(), EventId: "x", EventType: "x", Attempt: 1}); err == nil {
+		t.Fatalf("AddDelivery for an unknown webhook succeeded")
+	}
+}
+
+func mustCreateTask(t *testing.T, strg storage.StorageI, ownerID, title string) *task_service.GetTask {
+	t.Helper()
+	task, err := strg.Task().Create(context.Background(), &task_service.CreateTask{
+		UserId:     ownerID,
+		Title:      title,
+		TaskStatus: "new",
+	})
+	if err != nil {
+		t.Fatalf("Create task: %v", err)
+	}
+	return task
+}
+
+func testTaskLifecycle(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	owner := uuid.NewString()
+	created := mustCreateTask(t, strg, owner, "Write report")
+	if created.Id == "" || !strings.HasPrefix(created.ExternalId, "T") || created.CreatedAt == "" {
+		t.Fatalf("Create returned %+v", created)
+	}
+	key := &task_service.TaskPrimaryKey{Id: created.Id}
+
+	got, err := strg.Task().GetByExternalId(ctx, &task_service.TaskPrimaryKey{Id: created.ExternalId})
+	if err != nil || got.Id != created.Id {
+		t.Fatalf("GetByExternalId returned %v, %v", got, err)
+	}
+
+	updated, err := strg.Task().Update(ctx, &task_service.UpdateTask{Id: created.Id, UserId: owner, Title: "Write the report"})
+	if err != nil || updated.Title != "Write the report" || updated.UpdatedAt == "" {
+		t.Fatalf("Update returned %v, %v", updated, err)
+	}
+
+	old, err := strg.Task().ChangeStatus(ctx, &task_service.TaskChangeStatus{TaskId: created.Id, NewStatus: "done"})
+	if err != nil || old != "new" {
+		t.Fatalf("ChangeStatus returned %q, %v", old, err)
+	}
+	if got, _ = strg.Task().GetById(ctx, key); got.TaskStatus != "done" {
+		t.Fatalf("status after ChangeStatus is %q", got.TaskStatus)
+	}
+
+	mustCreateTask(t, strg, owner, "Send invoice")
+	list, err := strg.Task().GetAll(ctx, &task_service.GetListTaskRequest{OwnerId: owner, Search: "REPORT", Offset: 1, Limit: 10})
+	if err != nil || list.Count != 1 || len(list.Tasks) != 1 || list.Tasks[0].Id != created.Id {
+		t.Fatalf("GetAll by owner and search returned %v, %v", list, err)
+	}
+	list, err = strg.Task().GetAll(ctx, &task_service.GetListTaskRequest{OwnerId: owner, Limit: 1})
+	if err != nil || list.Count != 2 || len(list.Tasks) != 1 || list.Tasks[0].Id != created.Id {
+		t.Fatalf("GetAll with offset 0 must return the first page, got %v, %v", list, err)
+	}
+
+	if _, err = strg.Task().Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err = strg.Task().GetById(ctx, key); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetById of a deleted task must return pgx.ErrNoRows, got %v", err)
+	}
+	if _, err = strg.Task().Delete(ctx, key); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("Delete of a deleted task must return pgx.ErrNoRows, got %v", err)
+	}
+	if _, err = strg.Task().ChangeStatus(ctx, &task_service.TaskChangeStatus{TaskId: created.Id, NewStatus: "new"}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("ChangeStatus of a deleted task must return pgx.ErrNoRows, got %v", err)
+	}
+}
+
+func testTaskOwnerCascade(t *testing.T, strg storage.StorageI) {
+	ctx := context.Background()
+	owner := uuid.NewString()
+	first := mustCreateTask(t, strg, owner, "First")
+	second := mustCreateTask(t, strg, owner, "Second")
+	other := mustCreateTask(t, strg, uuid.NewString(), "Other owner")
+
+	// a task deleted on its own must stay deleted when the owner is restored
+	if _, err := strg.Task().Delete(ctx, &task_service.TaskPrimaryKey{Id: first.Id}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if n, err := strg.Task().DeleteByOwner(ctx, owner); err != nil || n != 1 {
+		t.Fatalf("DeleteByOwner returned %d, %v", n, err)
+	}
+	if n, err := strg.Task().DeleteByOwner(ctx, owner); err != nil || n != 0 {
+		t.Fatalf("repeated DeleteByOwner returned %d, %v", n, err)
+	}
+	if _, err := strg.Task().GetById(ctx, &task_service.TaskPrimaryKey{Id: other.Id}); err != nil {
+		t.Fatalf("DeleteByOwner touched another owner's task: %v", err)
+	}
+
+	if n, err := strg.Task().RestoreByOwner(ctx, owner); err != nil || n != 1 {
+		t.Fatalf("RestoreByOwner returned %d, %v", n, err)
+	}
+	if _, err := strg.Task().GetById(ctx, &task_service.TaskPrimaryKey{Id: second.Id}); err != nil {
+		t.Fatalf("GetById of a restored task: %v", err)
+	}
+	if _, err := strg.Task().GetById(ctx, &task_service.TaskPrimaryKey{Id: first.Id}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("RestoreByOwner brought back a task deleted on its own, got %v", err)
+	}
+
+	if n, err := strg.Task().PurgeByOwner(ctx, owner); err != nil || n != 2 {
+		t.Fatalf("PurgeByOwner returned %d, %v", n, err)
+	}
+	if n, err := strg.Task().RestoreByOwner(ctx, owner); err != nil || n != 0 {
+		t.Fatalf("RestoreByOwner after PurgeByOwner returned %d, %v", n, err)
+	}
+}
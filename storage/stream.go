@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseStreamID splits a stream id of the form "<ms>-<seq>".
+func ParseStreamID(id string) (ms, seq uint64, err error) {
+	msPart, seqPart, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid stream id %q", id)
+	}
+	if ms, err = strconv.ParseUint(msPart, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid stream id %q", id)
+	}
+	if seq, err = strconv.ParseUint(seqPart, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid stream id %q", id)
+	}
+	return ms, seq, nil
+}
+
+// CompareStreamIDs returns -1, 0 or 1 as a is before, equal to or after b.
+// Ids that don't parse sort first.
+func CompareStreamIDs(a, b string) int {
+	aMs, aSeq, _ := ParseStreamID(a)
+	bMs, bSeq, _ := ParseStreamID(b)
+	switch {
+	case aMs < bMs || (aMs == bMs && aSeq < bSeq):
+		return -1
+	case aMs == bMs && aSeq == bSeq:
+		return 0
+	default:
+		return 1
+	}
+}